@@ -1,6 +1,10 @@
 package main
 
-import "fmt"
+import (
+	"flag"
+	"fmt"
+	"time"
+)
 
 func ackermann(m, n int64) int64 {
 	if m == 0 {
@@ -12,7 +16,27 @@ func ackermann(m, n int64) int64 {
 	}
 }
 
+var presets = sizePresets{
+	"small":  {"n": "8"},
+	"medium": {"n": "10"},
+	"large":  {"n": "11"},
+	"huge":   {"n": "12"},
+}
+
 func main() {
-	result := ackermann(3, 10)
-	fmt.Printf("ackermann(3, 10) = %d\n", result)
+	parityFlag("ackermann.mml")
+	m := flag.Int64("m", 3, "first argument")
+	n := flag.Int64("n", 10, "second argument")
+	verify := flag.Bool("verify", false, "check the result against expected.go")
+	parseWithSize(presets)
+	defer startProfiling()()
+
+	start := startKernel()
+	result := ackermann(*m, *n)
+	elapsed := time.Since(start)
+	fmt.Printf("ackermann(%d, %d) = %d\n", *m, *n, result)
+	reportKernel("ackermann", defaultVariant, elapsed, result)
+	if *verify {
+		verifyResult("ackermann", fmt.Sprintf("m=%d n=%d", *m, *n), result)
+	}
 }
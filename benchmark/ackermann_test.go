@@ -0,0 +1,9 @@
+package main
+
+import "testing"
+
+func TestAckermannExpected(t *testing.T) {
+	testExpected(t, "ackermann", "n", 10, func(t *testing.T, c expectedCase) int64 {
+		return ackermann(c.int(t, "m"), c.int(t, "n"))
+	})
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"runtime"
+	"time"
+)
+
+// Every operation allocates a small object. A seeded fraction of them
+// (the survival rate) is parked in a fixed-size ring, where it stays live
+// until overwritten; the rest die immediately. Higher survival means
+// more live heap for the collector to trace and more objects that
+// outlive a GC cycle.
+
+type cell struct {
+	val  int64
+	next *cell
+}
+
+// Package-level sinks, so short-lived objects still escape to the heap.
+var (
+	sinkBox   *int64
+	sinkSlice []int64
+	sinkCell  *cell
+)
+
+// Drawn against this denominator, so the survival rate has 1ppm resolution.
+const survivalScale = 1_000_000
+
+func churnBoxed(ops int, keep int64, ring []*int64, r *lcg) {
+	slot := 0
+	for i := 0; i < ops; i++ {
+		p := new(int64)
+		*p = int64(i)
+		if r.below(survivalScale) < keep {
+			ring[slot] = p
+			slot = (slot + 1) % len(ring)
+		} else {
+			sinkBox = p
+		}
+	}
+}
+
+// Slices of 4 to 19 elements, so sizes span a few allocator classes.
+func churnSlices(ops int, keep int64, ring [][]int64, r *lcg) {
+	slot := 0
+	for i := 0; i < ops; i++ {
+		s := make([]int64, 4+i%16)
+		s[0] = int64(i)
+		if r.below(survivalScale) < keep {
+			ring[slot] = s
+			slot = (slot + 1) % len(ring)
+		} else {
+			sinkSlice = s
+		}
+	}
+}
+
+// Each operation conses a 4-cell list, the shape a functional
+// language produces for small intermediate lists.
+func churnCons(ops int, keep int64, ring []*cell, r *lcg) {
+	slot := 0
+	for i := 0; i < ops; i++ {
+		var head *cell
+		for k := 0; k < 4; k++ {
+			head = &cell{val: int64(i + k), next: head}
+		}
+		if r.below(survivalScale) < keep {
+			ring[slot] = head
+			slot = (slot + 1) % len(ring)
+		} else {
+			sinkCell = head
+		}
+	}
+}
+
+var presets = sizePresets{
+	"small":  {"n": "1000000"},
+	"medium": {"n": "10000000"},
+	"large":  {"n": "50000000"},
+	"huge":   {"n": "200000000"},
+}
+
+func main() {
+	ops := flag.Int("n", 10_000_000, "allocation operations")
+	survival := flag.Float64("survival", 0.01, "fraction of objects kept alive in the ring")
+	retain := flag.Int("retain", 1_000_000, "ring slots for surviving objects")
+	seed := flag.Int64("seed", 42, "LCG seed for the survival draws")
+	variant := flag.String("variant", "boxed", "boxed, slices or cons")
+	parseWithSize(presets)
+	defer startProfiling()()
+
+	if *survival < 0 || *survival > 1 || *retain < 1 {
+		fmt.Fprintln(os.Stderr, "-survival must be in [0, 1] and -retain at least 1")
+		exit(2)
+	}
+	keep := int64(*survival * survivalScale)
+	r := newLCG(*seed)
+
+	// The ring is allocated before the timed region and the survivors
+	// summed after it, so it covers the churn alone. The sum depends only
+	// on the seed.
+	var churn func()
+	var survivors func() int64
+	switch *variant {
+	case "boxed":
+		ring := make([]*int64, *retain)
+		churn = func() { churnBoxed(*ops, keep, ring, r) }
+		survivors = func() (sum int64) {
+			for _, p := range ring {
+				if p != nil {
+					sum += *p
+				}
+			}
+			return sum
+		}
+	case "slices":
+		ring := make([][]int64, *retain)
+		churn = func() { churnSlices(*ops, keep, ring, r) }
+		survivors = func() (sum int64) {
+			for _, s := range ring {
+				if s != nil {
+					sum += s[0] + int64(len(s))
+				}
+			}
+			return sum
+		}
+	case "cons":
+		ring := make([]*cell, *retain)
+		churn = func() { churnCons(*ops, keep, ring, r) }
+		survivors = func() (sum int64) {
+			for _, c := range ring {
+				for ; c != nil; c = c.next {
+					sum += c.val
+				}
+			}
+			return sum
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "unknown variant %q\n", *variant)
+		exit(2)
+	}
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	start := startKernel()
+	churn()
+	elapsed := time.Since(start)
+	runtime.ReadMemStats(&after)
+	checksum := survivors()
+
+	fmt.Printf("Allocation churn (%s, survival %g): checksum %d\n", *variant, *survival, checksum)
+
+	// Timing and GC numbers vary run to run, so they stay off stdout.
+	// HeapSys is what the runtime reserved for the heap, which never
+	// shrinks; -mem samples the peak actually in use.
+	gcs := after.NumGC - before.NumGC
+	pause := time.Duration(after.PauseTotalNs - before.PauseTotalNs)
+	fmt.Fprintf(os.Stderr, "ops/s: %.0f, allocs: %d, allocated: %d MiB, gc cycles: %d, gc pause total: %v, heap reserved: %d MiB\n",
+		float64(*ops)/elapsed.Seconds(),
+		after.Mallocs-before.Mallocs,
+		(after.TotalAlloc-before.TotalAlloc)>>20,
+		gcs, pause, after.HeapSys>>20)
+	reportKernel("alloc-churn", *variant, elapsed, checksum)
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"flag"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"time"
+)
+
+// Standard alphabet (RFC 4648) with '=' padding.
+const alphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/"
+
+// Reverse lookup: 0xFF marks bytes outside the alphabet.
+var decodeTable = makeDecodeTable()
+
+func makeDecodeTable() [256]byte {
+	var t [256]byte
+	for i := range t {
+		t[i] = 0xFF
+	}
+	for i := 0; i < len(alphabet); i++ {
+		t[alphabet[i]] = byte(i)
+	}
+	return t
+}
+
+func encodedLen(n int) int {
+	return (n + 2) / 3 * 4
+}
+
+// Three input bytes become one 24-bit group, split into four 6-bit indices.
+func encode(dst, src []byte) {
+	di, si := 0, 0
+	for ; si+3 <= len(src); si += 3 {
+		v := uint(src[si])<<16 | uint(src[si+1])<<8 | uint(src[si+2])
+		dst[di] = alphabet[v>>18&0x3F]
+		dst[di+1] = alphabet[v>>12&0x3F]
+		dst[di+2] = alphabet[v>>6&0x3F]
+		dst[di+3] = alphabet[v&0x3F]
+		di += 4
+	}
+
+	switch len(src) - si {
+	case 1:
+		v := uint(src[si]) << 16
+		dst[di] = alphabet[v>>18&0x3F]
+		dst[di+1] = alphabet[v>>12&0x3F]
+		dst[di+2] = '='
+		dst[di+3] = '='
+	case 2:
+		v := uint(src[si])<<16 | uint(src[si+1])<<8
+		dst[di] = alphabet[v>>18&0x3F]
+		dst[di+1] = alphabet[v>>12&0x3F]
+		dst[di+2] = alphabet[v>>6&0x3F]
+		dst[di+3] = '='
+	}
+}
+
+// Decodes padded input into dst and returns the number of bytes written,
+// or -1 if src is not valid base64.
+func decode(dst, src []byte) int {
+	if len(src)%4 != 0 {
+		return -1
+	}
+	di := 0
+	for si := 0; si < len(src); si += 4 {
+		a := decodeTable[src[si]]
+		b := decodeTable[src[si+1]]
+		if a > 63 || b > 63 {
+			return -1
+		}
+
+		last := si+4 == len(src)
+		if last && src[si+2] == '=' && src[si+3] == '=' {
+			dst[di] = a<<2 | b>>4
+			return di + 1
+		}
+		c := decodeTable[src[si+2]]
+		if c > 63 {
+			return -1
+		}
+		if last && src[si+3] == '=' {
+			dst[di] = a<<2 | b>>4
+			dst[di+1] = b<<4 | c>>2
+			return di + 2
+		}
+		d := decodeTable[src[si+3]]
+		if d > 63 {
+			return -1
+		}
+
+		v := uint(a)<<18 | uint(b)<<12 | uint(c)<<6 | uint(d)
+		dst[di] = byte(v >> 16)
+		dst[di+1] = byte(v >> 8)
+		dst[di+2] = byte(v)
+		di += 3
+	}
+	return di
+}
+
+var presets = sizePresets{
+	"small":  {"mb": "4"},
+	"medium": {"mb": "32"},
+	"large":  {"mb": "128"},
+	"huge":   {"mb": "512"},
+}
+
+func main() {
+	size := flag.Int("mb", 32, "input size in MiB")
+	reps := flag.Int("reps", 4, "encode+decode round trips")
+	seed := flag.Int64("seed", 42, "LCG seed for the input")
+	variant := flag.String("variant", "hand", "hand or stdlib")
+	parseWithSize(presets)
+	defer startProfiling()()
+
+	if *variant != "hand" && *variant != "stdlib" {
+		fmt.Fprintf(os.Stderr, "unknown variant %q\n", *variant)
+		exit(2)
+	}
+
+	// One byte short of a multiple of 3, so the padding path runs too
+	n := max(*size<<20-1, 0)
+	src := make([]byte, n)
+	r := newLCG(*seed)
+	for i := range src {
+		src[i] = byte(r.next() >> 32)
+	}
+
+	enc := make([]byte, encodedLen(n))
+	dec := make([]byte, n)
+	decoded := 0
+	start := startKernel()
+	for rep := 0; rep < *reps; rep++ {
+		if *variant == "hand" {
+			encode(enc, src)
+			decoded = decode(dec, enc)
+		} else {
+			base64.StdEncoding.Encode(enc, src)
+			var err error
+			decoded, err = base64.StdEncoding.Decode(dec, enc)
+			if err != nil {
+				decoded = -1
+			}
+		}
+	}
+	elapsed := time.Since(start)
+
+	if *reps > 0 {
+		if decoded != n || !bytes.Equal(dec, src) {
+			fmt.Fprintf(os.Stderr, "%s: round trip does not reproduce the input\n", *variant)
+			exit(1)
+		}
+		if want := base64.StdEncoding.EncodeToString(src); string(enc) != want {
+			fmt.Fprintf(os.Stderr, "%s: encoding differs from encoding/base64\n", *variant)
+			exit(1)
+		}
+	}
+
+	sum := crc32.ChecksumIEEE(enc)
+	fmt.Printf("Base64 round trips (%s): %d, encoded crc32: %08x\n", *variant, *reps, sum)
+	reportKernel("base64", *variant, elapsed, fmt.Sprintf("%08x", sum))
+}
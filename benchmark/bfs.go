@@ -0,0 +1,143 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Directed graph in CSR form: the out-neighbours of u are
+// adj[offsets[u]:offsets[u+1]].
+type graph struct {
+	offsets []int64
+	adj     []int64
+}
+
+// m uniformly random directed edges, bucketed by source with a
+// counting pass. Degrees vary, and some nodes end up unreachable.
+func buildGraph(n, m int, seed int64) *graph {
+	r := newLCG(seed)
+	src := make([]int64, m)
+	dst := make([]int64, m)
+	for e := 0; e < m; e++ {
+		src[e] = r.below(int64(n))
+		dst[e] = r.below(int64(n))
+	}
+
+	offsets := make([]int64, n+1)
+	for _, u := range src {
+		offsets[u+1]++
+	}
+	for u := 0; u < n; u++ {
+		offsets[u+1] += offsets[u]
+	}
+
+	adj := make([]int64, m)
+	fill := make([]int64, n)
+	copy(fill, offsets[:n])
+	for e, u := range src {
+		adj[fill[u]] = dst[e]
+		fill[u]++
+	}
+	return &graph{offsets: offsets, adj: adj}
+}
+
+// Level-synchronous BFS with a flat queue. level[v] is -1 when unreached.
+func bfs(g *graph, root int64, level, queue []int64) {
+	for i := range level {
+		level[i] = -1
+	}
+	level[root] = 0
+	queue[0] = root
+	head, tail := 0, 1
+	for head < tail {
+		u := queue[head]
+		head++
+		next := level[u] + 1
+		for e := g.offsets[u]; e < g.offsets[u+1]; e++ {
+			v := g.adj[e]
+			if level[v] < 0 {
+				level[v] = next
+				queue[tail] = v
+				tail++
+			}
+		}
+	}
+}
+
+// Checks level is exactly the BFS depth from root: no edge skips a level
+// forward or leaves the reached set, and every reached node other than
+// root has a parent exactly one level up.
+func verify(g *graph, root int64, level []int64) bool {
+	n := len(level)
+	hasParent := make([]bool, n)
+	for u := 0; u < n; u++ {
+		if level[u] < 0 {
+			continue
+		}
+		for e := g.offsets[u]; e < g.offsets[u+1]; e++ {
+			v := g.adj[e]
+			if level[v] < 0 || level[v] > level[u]+1 {
+				return false
+			}
+			if level[v] == level[u]+1 {
+				hasParent[v] = true
+			}
+		}
+	}
+	for v := 0; v < n; v++ {
+		if level[v] > 0 && !hasParent[v] {
+			return false
+		}
+	}
+	return level[root] == 0
+}
+
+var presets = sizePresets{
+	"small":  {"n": "100000", "m": "800000"},
+	"medium": {"n": "1000000", "m": "8000000"},
+	"large":  {"n": "4000000", "m": "32000000"},
+	"huge":   {"n": "16000000", "m": "128000000"},
+}
+
+func main() {
+	n := flag.Int("n", 1_000_000, "number of nodes")
+	m := flag.Int("m", 8_000_000, "number of directed edges")
+	sources := flag.Int("sources", 4, "BFS runs, each from a different root")
+	seed := flag.Int64("seed", 42, "LCG seed for the graph and roots")
+	parseWithSize(presets)
+	defer startProfiling()()
+
+	if *n < 1 {
+		fmt.Fprintln(os.Stderr, "-n must be at least 1")
+		exit(2)
+	}
+
+	g := buildGraph(*n, *m, *seed)
+	level := make([]int64, *n)
+	queue := make([]int64, *n)
+
+	r := newLCG(*seed + 1)
+	var visited, levelSum int64 = 0, 0
+	var elapsed time.Duration
+	for s := 0; s < *sources; s++ {
+		root := r.below(int64(*n))
+		start := startKernel()
+		bfs(g, root, level, queue)
+		elapsed += time.Since(start)
+		if !verify(g, root, level) {
+			fmt.Fprintf(os.Stderr, "levels from root %d are not BFS depths\n", root)
+			exit(1)
+		}
+		for _, l := range level {
+			if l >= 0 {
+				visited++
+				levelSum += l
+			}
+		}
+	}
+
+	fmt.Printf("BFS visited: %d, level sum: %d\n", visited, levelSum)
+	reportKernel("bfs", defaultVariant, elapsed, levelSum)
+}
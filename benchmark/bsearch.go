@@ -0,0 +1,111 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Classic binary search: exits early on a hit, and the
+// direction taken at every step is a data-dependent branch.
+func searchBranchy(arr []int64, key int64) bool {
+	lo, hi := 0, len(arr)-1
+	for lo <= hi {
+		mid := int(uint(lo+hi) >> 1)
+		v := arr[mid]
+		if v == key {
+			return true
+		} else if v < key {
+			lo = mid + 1
+		} else {
+			hi = mid - 1
+		}
+	}
+	return false
+}
+
+// Branchless binary search: the loop trip count depends only on len(arr),
+// and the step is selected with a sign mask instead of an if.
+// gc won't reliably turn the if-form into CMOV, so we spell it out.
+// Assumes arr[i] - key doesn't overflow, which holds for our inputs.
+func searchBranchless(arr []int64, key int64) bool {
+	base := 0
+	n := len(arr)
+	for n > 1 {
+		half := n / 2
+		// All ones when arr[base+half] <= key, zero otherwise
+		mask := int((arr[base+half] - key - 1) >> 63)
+		base += half & mask
+		n -= half
+	}
+	return arr[base] == key
+}
+
+var presets = sizePresets{
+	"small":  {"n": "65536", "lookups": "1000000"},
+	"medium": {"n": "1048576", "lookups": "5000000"},
+	"large":  {"n": "16777216", "lookups": "20000000"},
+	"huge":   {"n": "67108864", "lookups": "50000000"},
+}
+
+func main() {
+	n := flag.Int("n", 1<<20, "number of elements in the sorted array")
+	lookups := flag.Int("lookups", 5_000_000, "number of lookups")
+	seed := flag.Int64("seed", 42, "LCG seed for the queries")
+	variant := flag.String("variant", "branchy", "branchy or branchless")
+	parseWithSize(presets)
+	defer startProfiling()()
+
+	if *n < 1 {
+		fmt.Fprintln(os.Stderr, "-n must be at least 1")
+		exit(2)
+	}
+
+	// The array holds the odd numbers below 2n, so a query in [0, 2n)
+	// hits exactly when it is odd. That gives an independent expected count.
+	arr := make([]int64, *n)
+	for i := range arr {
+		arr[i] = int64(2*i + 1)
+	}
+
+	queries := make([]int64, *lookups)
+	r := newLCG(*seed)
+	span := int64(2 * *n)
+	var expected int64 = 0
+	for i := range queries {
+		q := r.below(span)
+		queries[i] = q
+		expected += q & 1
+	}
+
+	// One loop per variant, so each search can inline into it
+	var hits int64 = 0
+	start := startKernel()
+	switch *variant {
+	case "branchy":
+		for _, q := range queries {
+			if searchBranchy(arr, q) {
+				hits++
+			}
+		}
+	case "branchless":
+		for _, q := range queries {
+			if searchBranchless(arr, q) {
+				hits++
+			}
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "unknown variant %q\n", *variant)
+		exit(2)
+	}
+	elapsed := time.Since(start)
+
+	if hits != expected {
+		fmt.Fprintf(os.Stderr, "%s: got %d hits, expected %d\n", *variant, hits, expected)
+		exit(1)
+	}
+
+	fmt.Printf("Binary search hits (%s): %d\n", *variant, hits)
+	reportKernel("bsearch", *variant, elapsed, hits)
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Property checks: a kernel against a slow, obviously right reference on
+// many small random inputs, since the optimized variants keep drifting
+// from the plain ones. Benchmarks that have them take -check N:
+//
+//	bin/sieve-opt-go -check 1000
+//
+// The references and case generators live here, once per family, and
+// each variant passes its own kernel in. The family's _test.go runs the
+// same checks and fuzzes them; make test builds it against every variant.
+// The cases are the same on every run, so a failure reproduces.
+
+const checkSeed = 42
+
+// runChecks runs check on cases 0..cases-1, each with its own generator,
+// then exits: 0 if every case passed, 1 at the first failure.
+func runChecks(name string, cases int, check func(i int, r *lcg) error) {
+	for i := 0; i < cases; i++ {
+		if err := check(i, newLCG(streamSeed(checkSeed, i))); err != nil {
+			fmt.Fprintf(os.Stderr, "check %s: case %d: %v\n", name, i, err)
+			exit(1)
+		}
+	}
+	fmt.Fprintf(os.Stderr, "check %s: %d cases ok\n", name, cases)
+	exit(0)
+}
+
+// countPrimesSlow is the sieves' reference: trial division by every odd
+// number up to the square root.
+func countPrimesSlow(limit int64) int64 {
+	var count int64
+	for n := int64(2); n <= limit; n++ {
+		prime := n == 2 || n%2 == 1
+		for d := int64(3); prime && d*d <= n; d += 2 {
+			prime = n%d != 0
+		}
+		if prime {
+			count++
+		}
+	}
+	return count
+}
+
+// Every limit from 2 to 101 first, where the edge cases are, then random
+// ones up to 100000.
+func checkSieve(sieve func(limit int64) int64) func(i int, r *lcg) error {
+	return func(i int, r *lcg) error {
+		limit := int64(i) + 2
+		if i >= 100 {
+			limit = 2 + r.below(100_000)
+		}
+		return checkSieveLimit(sieve, limit)
+	}
+}
+
+func checkSieveLimit(sieve func(limit int64) int64, limit int64) error {
+	if got, want := sieve(limit), countPrimesSlow(limit); got != want {
+		return fmt.Errorf("limit=%d: got %d primes, want %d", limit, got, want)
+	}
+	return nil
+}
+
+// matMulSlow is the matmuls' reference: the textbook triple loop over
+// 2D indexing, no flattening tricks.
+func matMulSlow(A, B []int64, n int64) []int64 {
+	C := make([]int64, n*n)
+	for i := int64(0); i < n; i++ {
+		for j := int64(0); j < n; j++ {
+			for k := int64(0); k < n; k++ {
+				C[i*n+j] += A[i*n+k] * B[k*n+j]
+			}
+		}
+	}
+	return C
+}
+
+// The n×n matrix fillMatrix makes from seed.
+func randomMatrix(n, seed int64) []int64 {
+	m := make([]int64, n*n)
+	r := newLCG(seed)
+	for i := range m {
+		m[i] = r.next() % 100
+	}
+	return m
+}
+
+// Sizes 1 to 8 first, then random ones up to 48, each with random seeds.
+func checkMatMul(mul func(A, B, C []int64, n int64)) func(i int, r *lcg) error {
+	return func(i int, r *lcg) error {
+		n := int64(i) + 1
+		if i >= 8 {
+			n = 1 + r.below(48)
+		}
+		return checkMatMulSeeds(mul, n, r.next(), r.next())
+	}
+}
+
+// Compares every element, not just the trace.
+func checkMatMulSeeds(mul func(A, B, C []int64, n int64), n, seedA, seedB int64) error {
+	A, B := randomMatrix(n, seedA), randomMatrix(n, seedB)
+	C := make([]int64, n*n)
+	mul(A, B, C, n)
+	want := matMulSlow(A, B, n)
+	for k := range C {
+		if C[k] != want[k] {
+			return fmt.Errorf("n=%d seeds=%d,%d: C[%d][%d] = %d, want %d",
+				n, seedA, seedB, int64(k)/n, int64(k)%n, C[k], want[k])
+		}
+	}
+	return nil
+}
+
+// fizzbuzzSlow is the fizzbuzzes' reference: builds each line from its
+// parts instead of testing %15 first.
+func fizzbuzzSlow(n int) string {
+	var sb strings.Builder
+	for i := 1; i <= n; i++ {
+		line := ""
+		if i%3 == 0 {
+			line += "Fizz"
+		}
+		if i%5 == 0 {
+			line += "Buzz"
+		}
+		if line == "" {
+			line = strconv.Itoa(i)
+		}
+		sb.WriteString(line + "\n")
+	}
+	return sb.String()
+}
+
+// n from 0 to 30 first, then random ones up to 5000.
+func checkFizzbuzz(fizzbuzz func(n int, w io.Writer)) func(i int, r *lcg) error {
+	return func(i int, r *lcg) error {
+		n := i
+		if i > 30 {
+			n = int(r.below(5000))
+		}
+		return checkFizzbuzzN(fizzbuzz, n)
+	}
+}
+
+func checkFizzbuzzN(fizzbuzz func(n int, w io.Writer), n int) error {
+	var out bytes.Buffer
+	fizzbuzz(n, &out)
+	if want := fizzbuzzSlow(n); out.String() != want {
+		return fmt.Errorf("n=%d: output differs from the reference", n)
+	}
+	return nil
+}
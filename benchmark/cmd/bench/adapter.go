@@ -0,0 +1,136 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"path/filepath"
+	"regexp"
+	"strconv"
+)
+
+// The adapter for external binaries that know nothing about this
+// harness, like a freshly compiled MML sample: a fixed workload built
+// in, a result on stdout, maybe an MMLBENCH line. The suite file says
+// which expected.go entry the built-in workload corresponds to,
+//
+//	[[external]]
+//	name = "sieve-mml"
+//	command = "bin/sieve-mml"
+//	check = "sieve"
+//	workload = "limit=1000000"
+//
+// and bench run checks the first run's stdout against it before timing
+// the rest. The result is the last integer printed unless result gives a
+// regexp whose first group is it. Timing needs nothing: runOnce already
+// takes the MMLBENCH ns when there is one and wall time when not, and
+// the report says which rows are wall clock.
+
+// What an external's stdout must show.
+type expectation struct {
+	key    string // "bench params", as in expected.go
+	want   int64
+	result *regexp.Regexp
+}
+
+var lastInteger = regexp.MustCompile(`-?\d+`)
+
+func (e *expectation) check(stdout []byte) error {
+	var got []byte
+	if e.result != nil {
+		m := e.result.FindSubmatch(stdout)
+		if m == nil {
+			return fmt.Errorf("no match for %q in its output", e.result)
+		}
+		got = m[1]
+	} else {
+		all := lastInteger.FindAll(stdout, -1)
+		if all == nil {
+			return fmt.Errorf("no integer in its output")
+		}
+		got = all[len(all)-1]
+	}
+	n, err := strconv.ParseInt(string(got), 10, 64)
+	if err != nil {
+		return fmt.Errorf("result %q: %v", got, err)
+	}
+	if n != e.want {
+		return fmt.Errorf("printed %d, expected.go has %d for %s", n, e.want, e.key)
+	}
+	return nil
+}
+
+// The expectedResults table of expected.go, keyed "bench params". It's
+// read from the source because the benchmarks and this command can't
+// share a package; the benchmarks' -verify stays the one registry.
+func loadExpected(dir string) (map[string]int64, error) {
+	path := filepath.Join(dir, "expected.go")
+	f, err := parser.ParseFile(token.NewFileSet(), path, nil, 0)
+	if errors.Is(err, fs.ErrNotExist) {
+		return map[string]int64{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	table := map[string]int64{}
+	var bad error
+	ast.Inspect(f, func(n ast.Node) bool {
+		vs, ok := n.(*ast.ValueSpec)
+		if !ok || len(vs.Names) != 1 || vs.Names[0].Name != "expectedResults" || len(vs.Values) != 1 {
+			return true
+		}
+		lit, ok := vs.Values[0].(*ast.CompositeLit)
+		if !ok {
+			return false
+		}
+		for _, elt := range lit.Elts {
+			key, value, err := expectedEntry(elt)
+			if err != nil {
+				bad = fmt.Errorf("%s: %v", path, err)
+				return false
+			}
+			table[key] = value
+		}
+		return false
+	})
+	return table, bad
+}
+
+// One {"bench", "params"}: value element.
+func expectedEntry(elt ast.Expr) (string, int64, error) {
+	kv, ok := elt.(*ast.KeyValueExpr)
+	if !ok {
+		return "", 0, fmt.Errorf("expectedResults: unexpected element")
+	}
+	k, ok := kv.Key.(*ast.CompositeLit)
+	if !ok || len(k.Elts) != 2 {
+		return "", 0, fmt.Errorf("expectedResults: key isn't {bench, params}")
+	}
+	var parts [2]string
+	for i, e := range k.Elts {
+		s, ok := e.(*ast.BasicLit)
+		if !ok || s.Kind != token.STRING {
+			return "", 0, fmt.Errorf("expectedResults: key isn't two strings")
+		}
+		parts[i], _ = strconv.Unquote(s.Value)
+	}
+	v, neg := kv.Value, false
+	if u, ok := v.(*ast.UnaryExpr); ok && u.Op == token.SUB {
+		v, neg = u.X, true
+	}
+	n, ok := v.(*ast.BasicLit)
+	if !ok || n.Kind != token.INT {
+		return "", 0, fmt.Errorf("expectedResults[%s %s]: value isn't an integer literal", parts[0], parts[1])
+	}
+	value, err := strconv.ParseInt(n.Value, 0, 64)
+	if err != nil {
+		return "", 0, err
+	}
+	if neg {
+		value = -value
+	}
+	return parts[0] + " " + parts[1], value, nil
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// Totals from a cachegrind run. The field names are cachegrind's event
+// names: I = instruction fetch, D = data read (r) or write (w), 1 = L1,
+// L = last level, mr/mw = misses.
+type cacheStats struct {
+	ir, i1mr, ilmr int64
+	dr, d1mr, dlmr int64
+	dw, d1mw, dlmw int64
+}
+
+func (s cacheStats) i1MissRate() float64 { return ratio(s.i1mr, s.ir) }
+
+func (s cacheStats) d1MissRate() float64 { return ratio(s.d1mr+s.d1mw, s.dr+s.dw) }
+
+func (s cacheStats) llMissRate() float64 {
+	return ratio(s.ilmr+s.dlmr+s.dlmw, s.ir+s.dr+s.dw)
+}
+
+func ratio(a, b int64) float64 {
+	if b == 0 {
+		return 0
+	}
+	return float64(a) / float64(b)
+}
+
+// Runs cmd under cachegrind, writing its output file to out. Cache
+// simulation is off by default since valgrind 3.21, so ask for it.
+func cachegrind(cmd []string, out string) (cacheStats, error) {
+	args := append([]string{"--tool=cachegrind", "--cache-sim=yes", "--cachegrind-out-file=" + out}, cmd...)
+	c := exec.Command("valgrind", args...)
+	var stderr bytes.Buffer
+	c.Stderr = &stderr
+	if err := c.Run(); err != nil {
+		return cacheStats{}, fmt.Errorf("valgrind %s: %v\n%s", strings.Join(cmd, " "), err, stderr.String())
+	}
+	f, err := os.Open(out)
+	if err != nil {
+		return cacheStats{}, err
+	}
+	defer f.Close()
+	return parseCachegrind(f, out)
+}
+
+// The out file names its columns on an "events:" line and ends with a
+// "summary:" line holding the totals in that order.
+func parseCachegrind(f io.Reader, name string) (cacheStats, error) {
+	var events, summary []string
+	sc := bufio.NewScanner(f)
+	sc.Buffer(nil, 1<<20)
+	for sc.Scan() {
+		line := sc.Text()
+		if rest, ok := strings.CutPrefix(line, "events:"); ok {
+			events = strings.Fields(rest)
+		} else if rest, ok := strings.CutPrefix(line, "summary:"); ok {
+			summary = strings.Fields(rest)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return cacheStats{}, err
+	}
+	if events == nil || summary == nil {
+		return cacheStats{}, fmt.Errorf("%s: no events or summary line", name)
+	}
+
+	var s cacheStats
+	fields := map[string]*int64{
+		"Ir": &s.ir, "I1mr": &s.i1mr, "ILmr": &s.ilmr,
+		"Dr": &s.dr, "D1mr": &s.d1mr, "DLmr": &s.dlmr,
+		"Dw": &s.dw, "D1mw": &s.d1mw, "DLmw": &s.dlmw,
+	}
+	for i, ev := range events {
+		p, ok := fields[ev]
+		if !ok || i >= len(summary) {
+			continue
+		}
+		v, err := strconv.ParseInt(summary[i], 10, 64)
+		if err != nil {
+			return cacheStats{}, fmt.Errorf("%s: summary %s: %v", name, ev, err)
+		}
+		*p = v
+	}
+	if s.ir == 0 {
+		return cacheStats{}, fmt.Errorf("%s: no instruction count", name)
+	}
+	return s, nil
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// The header of a real cachegrind.out, trimmed.
+const cachegrindOut = `desc: I1 cache:         32768 B, 64 B, 8-way associative
+desc: D1 cache:         32768 B, 64 B, 8-way associative
+desc: LL cache:         8388608 B, 64 B, 16-way associative
+cmd: bin/sieve-go -size small
+events: Ir I1mr ILmr Dr D1mr DLmr Dw D1mw DLmw
+fl=(1) ???
+fn=(1) main.runSieve
+12 4000 2 2 1000 100 10 500 50 5
+summary: 4000 2 2 1000 100 10 500 50 5
+`
+
+func TestParseCachegrind(t *testing.T) {
+	for _, tc := range []struct {
+		name, in string
+		want     cacheStats
+		err      string // substring of the error, "" for none
+	}{
+		{name: "full", in: cachegrindOut, want: cacheStats{
+			ir: 4000, i1mr: 2, ilmr: 2,
+			dr: 1000, d1mr: 100, dlmr: 10,
+			dw: 500, d1mw: 50, dlmw: 5,
+		}},
+		// --cache-sim=no: only Ir.
+		{name: "instructions only", in: "events: Ir\nsummary: 1234\n", want: cacheStats{ir: 1234}},
+		// Columns go by the events line, not by position.
+		{name: "reordered", in: "events: Dr Ir\nsummary: 7 9\n", want: cacheStats{ir: 9, dr: 7}},
+		{name: "unknown events skipped", in: "events: Ir Bc Bcm\nsummary: 10 3 1\n", want: cacheStats{ir: 10}},
+		{name: "short summary", in: "events: Ir Dr Dw\nsummary: 10\n", want: cacheStats{ir: 10}},
+		{name: "empty", in: "", err: "no events or summary"},
+		{name: "no summary", in: "events: Ir\n", err: "no events or summary"},
+		{name: "no events", in: "summary: 10\n", err: "no events or summary"},
+		{name: "bad count", in: "events: Ir\nsummary: 1x\n", err: "summary Ir"},
+		{name: "no instructions", in: "events: Dr\nsummary: 10\n", err: "no instruction count"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := parseCachegrind(strings.NewReader(tc.in), "out")
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("got %+v, %v; want error containing %q", got, err, tc.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.want {
+				t.Errorf("got %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestCacheMissRates(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		s          cacheStats
+		i1, d1, ll float64
+	}{
+		{"sample", cacheStats{ir: 4000, i1mr: 2, ilmr: 2, dr: 1000, d1mr: 100, dlmr: 10, dw: 500, d1mw: 50, dlmw: 5},
+			0.0005, 0.1, 17.0 / 5500},
+		// No data accesses: 0, not NaN.
+		{"no data", cacheStats{ir: 10, i1mr: 1}, 0.1, 0, 0},
+		{"zero", cacheStats{}, 0, 0, 0},
+	} {
+		if got := tc.s.i1MissRate(); got != tc.i1 {
+			t.Errorf("%s: I1 miss rate %v, want %v", tc.name, got, tc.i1)
+		}
+		if got := tc.s.d1MissRate(); got != tc.d1 {
+			t.Errorf("%s: D1 miss rate %v, want %v", tc.name, got, tc.d1)
+		}
+		if got := tc.s.llMissRate(); got != tc.ll {
+			t.Errorf("%s: LL miss rate %v, want %v", tc.name, got, tc.ll)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// Noise calibration: time a fixed, cache-resident integer kernel a few
+// times and take the coefficient of variation. On a quiet machine it
+// sits well under 1%; a laptop on battery or a compile in the background
+// pushes it to several percent, and then a 2% delta in the suite means
+// nothing.
+
+const calibrationRuns = 10
+
+var calibrationSink uint64
+
+// About 10ms on a current x86 core. xorshift so the compiler can't fold
+// the loop and there's no memory traffic to add its own noise.
+func calibrationKernel() {
+	x := uint64(0x9E3779B97F4A7C15)
+	for i := 0; i < 4_000_000; i++ {
+		x ^= x << 13
+		x ^= x >> 7
+		x ^= x << 17
+	}
+	calibrationSink += x
+}
+
+type noise struct {
+	mean time.Duration
+	cv   float64
+}
+
+func (n noise) String() string {
+	return fmt.Sprintf("cv %.2f%% over %d calibration runs of %v", 100*n.cv, calibrationRuns, n.mean.Round(time.Microsecond))
+}
+
+func calibrate() noise {
+	calibrationKernel() // warm up
+	ts := make([]float64, calibrationRuns)
+	var sum float64
+	for i := range ts {
+		start := time.Now()
+		calibrationKernel()
+		ts[i] = float64(time.Since(start))
+		sum += ts[i]
+	}
+	mean := sum / float64(len(ts))
+	var ss float64
+	for _, t := range ts {
+		ss += (t - mean) * (t - mean)
+	}
+	sd := math.Sqrt(ss / float64(len(ts)-1))
+	return noise{mean: time.Duration(mean), cv: sd / mean}
+}
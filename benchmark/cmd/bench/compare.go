@@ -0,0 +1,185 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// bench compare: run several commands on the same workload and compare
+// them against the first, e.g.
+//
+//	bench compare 'bin/sieve-c' 'bin/sieve-go' 'bin/sieve-mml'
+//
+// Each argument is one command line, split on spaces. The commands can
+// be any executables, not just the Go benchmarks. MML binaries can't
+// take arguments yet, so give both sides the workload through -env:
+//
+//	bench compare -env MMLBENCH_LIMIT=10000000 bin/sieve-go bin/sieve-mml
+func runCompare(args []string) int {
+	fs := flag.NewFlagSet("compare", flag.ExitOnError)
+	tool := fs.String("tool", "time", "time, split (kernel vs startup) or cachegrind")
+	runs := fs.Int("runs", 10, "runs per command with -tool=time; the median is reported")
+	alpha := fs.Float64("alpha", 0.05, "significance level for -tool=time; larger p-values print as ~")
+	fs.Func("env", "set `NAME=VALUE` in the environment of every command (repeatable)", func(kv string) error {
+		name, value, ok := strings.Cut(kv, "=")
+		if !ok || name == "" {
+			return fmt.Errorf("want NAME=VALUE, got %q", kv)
+		}
+		return os.Setenv(name, value)
+	})
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: bench compare [flags] 'command args' 'command args'...")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() < 1 {
+		fs.Usage()
+		return 2
+	}
+	if *runs < 1 {
+		fmt.Fprintln(os.Stderr, "-runs must be at least 1")
+		return 2
+	}
+	var cmds [][]string
+	for _, c := range fs.Args() {
+		f := strings.Fields(c)
+		if len(f) == 0 {
+			fmt.Fprintln(os.Stderr, "empty command")
+			return 2
+		}
+		cmds = append(cmds, f)
+	}
+
+	switch *tool {
+	case "time":
+		return compareTime(fs.Args(), cmds, *runs, *alpha)
+	case "split":
+		return compareSplit(fs.Args(), cmds, *runs, *alpha)
+	case "cachegrind":
+		return compareCachegrind(fs.Args(), cmds)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown tool %q\n", *tool)
+		return 2
+	}
+}
+
+// Runs are interleaved, one of each command per round, so drift in
+// machine state (thermals, other load) hits every command alike. Each
+// command is tested against the first with Mann-Whitney U.
+func compareTime(labels []string, cmds [][]string, runs int, alpha float64) int {
+	samples := make([][]time.Duration, len(cmds))
+	for i := 0; i < runs; i++ {
+		for j, c := range cmds {
+			s, err := runOnce(c[0], c[1:], nil)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return 1
+			}
+			samples[j] = append(samples[j], s.kernel)
+		}
+	}
+
+	fmt.Println(describeHost())
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "command\tmedian\tdelta")
+	for j, label := range labels {
+		d := "base"
+		if j > 0 {
+			d = significance(samples[j], samples[0], alpha)
+		}
+		fmt.Fprintf(tw, "%s\t%v\t%s\n", label, median(samples[j]).Round(time.Microsecond), d)
+	}
+	tw.Flush()
+	return 0
+}
+
+// -tool=split: wall time next to the kernel time from the MMLBENCH line,
+// and what's left over, which is mostly process startup and teardown (Go
+// runtime init, page faults, input setup). On small inputs that overhead
+// is what makes a Go binary look slower than a near-zero-startup MML
+// one; this keeps the two apart. Kernel deltas are tested as in -tool
+// time. Commands that don't print MMLBENCH lines only get a wall time.
+func compareSplit(labels []string, cmds [][]string, runs int, alpha float64) int {
+	kernels := make([][]time.Duration, len(cmds))
+	walls := make([][]time.Duration, len(cmds))
+	selfTimed := make([]bool, len(cmds))
+	for i := 0; i < runs; i++ {
+		for j, c := range cmds {
+			s, err := runOnce(c[0], c[1:], nil)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return 1
+			}
+			kernels[j] = append(kernels[j], s.kernel)
+			walls[j] = append(walls[j], s.wall)
+			selfTimed[j] = s.selfTimed
+		}
+	}
+
+	fmt.Println(describeHost())
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "command\twall\tkernel\tstartup+exit\tkernel delta")
+	for j, label := range labels {
+		wall := median(walls[j])
+		if !selfTimed[j] {
+			fmt.Fprintf(tw, "%s\t%v\t-\t-\t-\n", label, wall.Round(time.Microsecond))
+			continue
+		}
+		d := "base"
+		if j > 0 && selfTimed[0] {
+			d = significance(kernels[j], kernels[0], alpha)
+		} else if j > 0 {
+			d = "-"
+		}
+		kernel := median(kernels[j])
+		fmt.Fprintf(tw, "%s\t%v\t%v\t%v\t%s\n", label, wall.Round(time.Microsecond),
+			kernel.Round(time.Microsecond), (wall - kernel).Round(time.Microsecond), d)
+	}
+	tw.Flush()
+	return 0
+}
+
+// One run each: the counts are simulated, so they don't vary run to run.
+func compareCachegrind(labels []string, cmds [][]string) int {
+	dir, err := os.MkdirTemp("", "bench-cachegrind")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer os.RemoveAll(dir)
+
+	stats := make([]cacheStats, len(cmds))
+	for j, c := range cmds {
+		fmt.Fprintf(os.Stderr, "%s...\n", labels[j])
+		out := filepath.Join(dir, fmt.Sprintf("cachegrind.out.%d", j))
+		if stats[j], err = cachegrind(c, out); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	}
+
+	fmt.Println(describeHost())
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "command\tinstructions\tdelta\tI1 miss\tD1 miss\tLL miss")
+	base := stats[0].ir
+	for j, label := range labels {
+		s := stats[j]
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%.2f%%\t%.2f%%\t%.2f%%\n", label, s.ir, countDelta(s.ir, base),
+			100*s.i1MissRate(), 100*s.d1MissRate(), 100*s.llMissRate())
+	}
+	tw.Flush()
+	return 0
+}
+
+func countDelta(v, base int64) string {
+	if base == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%+.2f%%", 100*(float64(v)-float64(base))/float64(base))
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// A suite file pins down a whole comparison campaign, so it can be
+// committed and rerun with bench run -c suite.toml:
+//
+//	runs = 10
+//	sizes = ["small", "medium"]
+//
+//	[[benchmark]]
+//	name = "sort"
+//	variants = ["quick", "merge"]
+//
+//	[[external]]
+//	name = "sieve-mml"
+//	command = "bin/sieve-mml"
+//	env = ["MMLBENCH_LIMIT=1000000"]
+//
+//	[[external]]
+//	name = "lines-mml"
+//	command = "bin/lines-mml"
+//	input = "lines"
+//	size = "medium"
+//
+// Each benchmark runs once per variant per size. Externals are any
+// other executables, typically the MML builds; they get no -size, so
+// their workload goes in env (or their own arguments in command). An
+// external with an input gets that corpus (see geninput.go) on stdin,
+// the same file the Go benchmark reads with -file. check, workload and
+// result check an external's output against expected.go (adapter.go).
+
+// The -size presets every benchmark has; sizeNames in sizes.go.
+var suiteSizes = []string{"small", "medium", "large", "huge"}
+
+type suiteConfig struct {
+	runs       int
+	sizes      []string
+	args       string
+	benchmarks []suiteBenchmark
+	externals  []suiteExternal
+}
+
+type suiteBenchmark struct {
+	name     string
+	variants []string // empty: the benchmark's default
+	sizes    []string // empty: the suite's
+	args     string
+}
+
+type suiteExternal struct {
+	name    string
+	command string
+	env     []string
+	input   string // corpus on stdin
+	size    string // of the input
+	// expected.go entry for its built-in workload, and how to find the
+	// result on stdout (default: the last integer)
+	check, workload, result string
+}
+
+func loadSuite(path string) (suiteConfig, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return suiteConfig{}, err
+	}
+	doc, err := parseTOML(string(src))
+	if err != nil {
+		return suiteConfig{}, fmt.Errorf("%s: %v", path, err)
+	}
+	c, err := decodeSuite(doc)
+	if err != nil {
+		return suiteConfig{}, fmt.Errorf("%s: %v", path, err)
+	}
+	return c, nil
+}
+
+func decodeSuite(doc tomlTable) (suiteConfig, error) {
+	var c suiteConfig
+	d := tomlDecoder{t: doc, where: "top level"}
+	c.runs = int(d.int("runs"))
+	c.sizes = d.strings("sizes")
+	c.args = d.string("args")
+	benches := d.tables("benchmark")
+	externals := d.tables("external")
+	if err := d.finish(); err != nil {
+		return c, err
+	}
+	for _, s := range c.sizes {
+		if !slices.Contains(suiteSizes, s) {
+			return c, fmt.Errorf("unknown size %q, want one of %v", s, suiteSizes)
+		}
+	}
+
+	for i, t := range benches {
+		d := tomlDecoder{t: t, where: fmt.Sprintf("benchmark %d", i+1)}
+		b := suiteBenchmark{
+			name:     d.string("name"),
+			variants: d.strings("variants"),
+			sizes:    d.strings("sizes"),
+			args:     d.string("args"),
+		}
+		if err := d.finish(); err != nil {
+			return c, err
+		}
+		if b.name == "" {
+			return c, fmt.Errorf("benchmark %d: no name", i+1)
+		}
+		for _, s := range b.sizes {
+			if !slices.Contains(suiteSizes, s) {
+				return c, fmt.Errorf("benchmark %s: unknown size %q", b.name, s)
+			}
+		}
+		c.benchmarks = append(c.benchmarks, b)
+	}
+	for i, t := range externals {
+		d := tomlDecoder{t: t, where: fmt.Sprintf("external %d", i+1)}
+		e := suiteExternal{
+			name:     d.string("name"),
+			command:  d.string("command"),
+			env:      d.strings("env"),
+			input:    d.string("input"),
+			size:     d.string("size"),
+			check:    d.string("check"),
+			workload: d.string("workload"),
+			result:   d.string("result"),
+		}
+		if err := d.finish(); err != nil {
+			return c, err
+		}
+		if e.name == "" || e.command == "" {
+			return c, fmt.Errorf("external %d: needs a name and a command", i+1)
+		}
+		if (e.check == "") != (e.workload == "") || e.result != "" && e.check == "" {
+			return c, fmt.Errorf("external %s: check and workload go together, and result needs them", e.name)
+		}
+		if e.result != "" {
+			re, err := regexp.Compile(e.result)
+			if err != nil {
+				return c, fmt.Errorf("external %s: result: %v", e.name, err)
+			}
+			if re.NumSubexp() < 1 {
+				return c, fmt.Errorf("external %s: result needs a (group) around the number", e.name)
+			}
+		}
+		if _, ok := corpora[e.input]; e.input != "" && !ok {
+			return c, fmt.Errorf("external %s: no input corpus %q", e.name, e.input)
+		}
+		if e.size != "" && !slices.Contains(suiteSizes, e.size) {
+			return c, fmt.Errorf("external %s: unknown size %q", e.name, e.size)
+		}
+		for _, kv := range e.env {
+			if !strings.Contains(kv, "=") {
+				return c, fmt.Errorf("external %s: env wants NAME=VALUE, got %q", e.name, kv)
+			}
+		}
+		c.externals = append(c.externals, e)
+	}
+	return c, nil
+}
+
+// Typed access to one table. The first error sticks, and finish also
+// reports keys nobody asked for, so typos don't pass silently.
+type tomlDecoder struct {
+	t     tomlTable
+	where string
+	used  []string
+	err   error
+}
+
+func (d *tomlDecoder) get(key string) (any, bool) {
+	d.used = append(d.used, key)
+	v, ok := d.t[key]
+	return v, ok
+}
+
+func (d *tomlDecoder) fail(key, want string, v any) {
+	if d.err == nil {
+		d.err = fmt.Errorf("%s: %s should be %s, got %v", d.where, key, want, v)
+	}
+}
+
+func (d *tomlDecoder) string(key string) string {
+	v, ok := d.get(key)
+	if !ok {
+		return ""
+	}
+	s, ok := v.(string)
+	if !ok {
+		d.fail(key, "a string", v)
+	}
+	return s
+}
+
+func (d *tomlDecoder) int(key string) int64 {
+	v, ok := d.get(key)
+	if !ok {
+		return 0
+	}
+	n, ok := v.(int64)
+	if !ok {
+		d.fail(key, "an integer", v)
+	}
+	return n
+}
+
+func (d *tomlDecoder) strings(key string) []string {
+	v, ok := d.get(key)
+	if !ok {
+		return nil
+	}
+	list, ok := v.([]any)
+	if !ok {
+		d.fail(key, "an array of strings", v)
+		return nil
+	}
+	out := make([]string, 0, len(list))
+	for _, e := range list {
+		s, ok := e.(string)
+		if !ok {
+			d.fail(key, "an array of strings", v)
+			return nil
+		}
+		out = append(out, s)
+	}
+	return out
+}
+
+func (d *tomlDecoder) tables(key string) []tomlTable {
+	v, ok := d.get(key)
+	if !ok {
+		return nil
+	}
+	list, ok := v.([]tomlTable)
+	if !ok {
+		d.fail(key, "[["+key+"]] tables", v)
+	}
+	return list
+}
+
+func (d *tomlDecoder) finish() error {
+	if d.err != nil {
+		return d.err
+	}
+	var unknown []string
+	for k := range d.t {
+		if !slices.Contains(d.used, k) {
+			unknown = append(unknown, k)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("%s: unknown keys %s", d.where, strings.Join(unknown, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func decodeSuiteString(t *testing.T, src string) (suiteConfig, error) {
+	t.Helper()
+	doc, err := parseTOML(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return decodeSuite(doc)
+}
+
+func TestDecodeSuite(t *testing.T) {
+	got, err := decodeSuiteString(t, `
+runs = 10
+sizes = ["small", "medium"]
+args = "-parity"
+
+[[benchmark]]
+name = "sort"
+variants = ["quick", "merge"]
+
+[[benchmark]]
+name = "sieve"
+sizes = ["large"]
+args = "-kernel bitset"
+
+[[external]]
+name = "sieve-mml"
+command = "bin/sieve-mml"
+env = ["MMLBENCH_LIMIT=1000000"]
+check = "sieve"
+workload = "limit=1000000"
+result = "primes: (\\d+)"
+
+[[external]]
+name = "lines-mml"
+command = "bin/lines-mml"
+input = "lines"
+size = "medium"
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := suiteConfig{
+		runs:  10,
+		sizes: []string{"small", "medium"},
+		args:  "-parity",
+		benchmarks: []suiteBenchmark{
+			{name: "sort", variants: []string{"quick", "merge"}},
+			{name: "sieve", sizes: []string{"large"}, args: "-kernel bitset"},
+		},
+		externals: []suiteExternal{
+			{name: "sieve-mml", command: "bin/sieve-mml", env: []string{"MMLBENCH_LIMIT=1000000"},
+				check: "sieve", workload: "limit=1000000", result: `primes: (\d+)`},
+			{name: "lines-mml", command: "bin/lines-mml", input: "lines", size: "medium"},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got  %+v\nwant %+v", got, want)
+	}
+}
+
+func TestDecodeSuiteEmpty(t *testing.T) {
+	got, err := decodeSuiteString(t, "# nothing yet\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, suiteConfig{}) {
+		t.Errorf("got %+v, want the zero config", got)
+	}
+}
+
+func TestDecodeSuiteErrors(t *testing.T) {
+	for _, tc := range []struct{ name, in, err string }{
+		{"unknown key", "runs = 1\nrusn = 2", "top level: unknown keys rusn"},
+		{"unknown keys sorted", "b = 1\na = 2", "top level: unknown keys a, b"},
+		{"runs type", `runs = "10"`, "top level: runs should be an integer, got 10"},
+		{"sizes type", `sizes = "small"`, "top level: sizes should be an array of strings"},
+		{"sizes element type", `sizes = ["small", 2]`, "top level: sizes should be an array of strings"},
+		{"args type", "args = true", "top level: args should be a string, got true"},
+		{"benchmark not tables", "benchmark = 1", "top level: benchmark should be [[benchmark]] tables"},
+		{"benchmark table", "[benchmark]\nname = \"a\"", "benchmark should be [[benchmark]] tables"},
+		{"unknown size", `sizes = ["tiny"]`, `unknown size "tiny"`},
+		{"no name", "[[benchmark]]\nvariants = [\"quick\"]", "benchmark 1: no name"},
+		{"benchmark key", "[[benchmark]]\nname = \"sort\"\nvariant = \"quick\"", "benchmark 1: unknown keys variant"},
+		{"benchmark size", "[[benchmark]]\nname = \"sort\"\nsizes = [\"tiny\"]", `benchmark sort: unknown size "tiny"`},
+		{"second benchmark", "[[benchmark]]\nname = \"a\"\n[[benchmark]]\nname = 1", "benchmark 2: name should be a string"},
+		{"no command", "[[external]]\nname = \"x\"", "external 1: needs a name and a command"},
+		{"no external name", "[[external]]\ncommand = \"bin/x\"", "external 1: needs a name and a command"},
+		{"external key", "[[external]]\nname = \"x\"\ncommand = \"bin/x\"\nstdin = \"lines\"", "external 1: unknown keys stdin"},
+		{"check alone", "[[external]]\nname = \"x\"\ncommand = \"bin/x\"\ncheck = \"sieve\"", "external x: check and workload go together"},
+		{"workload alone", "[[external]]\nname = \"x\"\ncommand = \"bin/x\"\nworkload = \"limit=1\"", "check and workload go together"},
+		{"result alone", "[[external]]\nname = \"x\"\ncommand = \"bin/x\"\nresult = \"(\\\\d+)\"", "result needs them"},
+		{"bad result", "[[external]]\nname = \"x\"\ncommand = \"bin/x\"\ncheck = \"sieve\"\nworkload = \"limit=1\"\nresult = \"(\"",
+			"external x: result: error parsing regexp"},
+		{"result group", "[[external]]\nname = \"x\"\ncommand = \"bin/x\"\ncheck = \"sieve\"\nworkload = \"limit=1\"\nresult = \"\\\\d+\"",
+			"external x: result needs a (group)"},
+		{"unknown input", "[[external]]\nname = \"x\"\ncommand = \"bin/x\"\ninput = \"dna\"", `external x: no input corpus "dna"`},
+		{"external size", "[[external]]\nname = \"x\"\ncommand = \"bin/x\"\ninput = \"lines\"\nsize = \"tiny\"", `external x: unknown size "tiny"`},
+		{"env", "[[external]]\nname = \"x\"\ncommand = \"bin/x\"\nenv = [\"LIMIT\"]", `external x: env wants NAME=VALUE, got "LIMIT"`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := decodeSuiteString(t, tc.in)
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Fatalf("got %+v, %v; want error containing %q", got, err, tc.err)
+			}
+		})
+	}
+}
+
+func TestLoadSuite(t *testing.T) {
+	// The committed suite decodes.
+	c, err := loadSuite("../../suite.toml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.benchmarks) == 0 || len(c.externals) == 0 {
+		t.Errorf("suite.toml: %d benchmarks and %d externals", len(c.benchmarks), len(c.externals))
+	}
+
+	// Errors name the file.
+	dir := t.TempDir()
+	for name, src := range map[string]string{
+		"syntax.toml": "runs = ",
+		"decode.toml": "rusn = 1",
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := loadSuite(path); err == nil || !strings.HasPrefix(err.Error(), path+": ") {
+			t.Errorf("%s: got %v, want an error naming the file", name, err)
+		}
+	}
+	if _, err := loadSuite(filepath.Join(dir, "missing.toml")); !os.IsNotExist(err) {
+		t.Errorf("missing file: got %v", err)
+	}
+}
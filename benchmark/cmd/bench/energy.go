@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Energy from Intel RAPL (AMD Zen exposes the same interface) through
+// the powercap sysfs tree:
+//
+//	/sys/class/powercap/intel-rapl:0/name        package-0
+//	/sys/class/powercap/intel-rapl:0/energy_uj   cumulative microjoules
+//	/sys/class/powercap/intel-rapl:0:1/name      dram
+//
+// The counters cover the whole socket, not our process, so a quiet
+// machine matters even more here than for timing. Recent kernels make
+// energy_uj readable by root only.
+
+const defaultPowercap = "/sys/class/powercap"
+
+type raplZone struct {
+	domain   string // "package" or "dram"
+	path     string
+	maxRange int64 // energy_uj wraps around at this value
+}
+
+type energy struct {
+	pkg  float64 // joules, summed over packages
+	dram float64
+	// No DRAM zone: many client parts and AMD don't have one
+	hasDRAM bool
+}
+
+// The package and DRAM zones under root, or an error saying why energy
+// can't be measured.
+func findRAPL(root string) ([]raplZone, error) {
+	dirs, err := filepath.Glob(filepath.Join(root, "intel-rapl:*"))
+	if err != nil {
+		return nil, err
+	}
+	var zones []raplZone
+	for _, dir := range dirs {
+		name, err := os.ReadFile(filepath.Join(dir, "name"))
+		if err != nil {
+			continue
+		}
+		var domain string
+		switch n := strings.TrimSpace(string(name)); {
+		case strings.HasPrefix(n, "package-"):
+			domain = "package"
+		case n == "dram":
+			domain = "dram"
+		default:
+			continue
+		}
+		z := raplZone{domain: domain, path: filepath.Join(dir, "energy_uj")}
+		if z.maxRange, err = readInt(filepath.Join(dir, "max_energy_range_uj")); err != nil {
+			return nil, err
+		}
+		// Fail now on permissions rather than on the first run
+		if _, err := readInt(z.path); err != nil {
+			return nil, err
+		}
+		zones = append(zones, z)
+	}
+	if len(zones) == 0 {
+		return nil, fmt.Errorf("no RAPL package zones under %s", root)
+	}
+	return zones, nil
+}
+
+func readInt(path string) (int64, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(strings.TrimSpace(string(b)), 10, 64)
+}
+
+// Raw counter values, in the order of zones.
+func readRAPL(zones []raplZone) ([]int64, error) {
+	uj := make([]int64, len(zones))
+	for i, z := range zones {
+		v, err := readInt(z.path)
+		if err != nil {
+			return nil, err
+		}
+		uj[i] = v
+	}
+	return uj, nil
+}
+
+// Energy used between two readings. A run can see at most one wrap:
+// the ranges are hundreds of kJ, minutes of full load.
+func energyBetween(zones []raplZone, before, after []int64) energy {
+	var e energy
+	for i, z := range zones {
+		d := after[i] - before[i]
+		if d < 0 {
+			d += z.maxRange
+		}
+		j := float64(d) / 1e6
+		if z.domain == "dram" {
+			e.dram += j
+			e.hasDRAM = true
+		} else {
+			e.pkg += j
+		}
+	}
+	return e
+}
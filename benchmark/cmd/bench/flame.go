@@ -0,0 +1,270 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// bench flame: fold the stacks of a profile and draw them as a flame
+// graph. Takes a pprof profile from -cpuprofile (bench pgo leaves one per
+// benchmark), or perf data for binaries we can't build with pprof, like
+// the MML ones:
+//
+//	perf record -g -o sieve.perf bin/sieve-mml
+//	bench flame sieve.perf
+//
+// Writes <out>.folded, one "root;...;leaf value" line per stack as
+// flamegraph.pl and speedscope read them, and <out>.svg.
+func runFlame(args []string) int {
+	fs := flag.NewFlagSet("flame", flag.ExitOnError)
+	out := fs.String("o", "", "output path without extension (default: the input's)")
+	title := fs.String("title", "", "graph title (default: the input file name)")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: bench flame [flags] profile")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+	in := fs.Arg(0)
+	if *out == "" {
+		*out = strings.TrimSuffix(in, ".pprof")
+		*out = strings.TrimSuffix(*out, ".data")
+		*out = strings.TrimSuffix(*out, ".perf")
+	}
+	if *title == "" {
+		*title = in
+	}
+
+	stacks, unit, err := foldProfile(in)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if len(stacks) == 0 {
+		fmt.Fprintf(os.Stderr, "%s: no samples\n", in)
+		return 1
+	}
+	if err := os.WriteFile(*out+".folded", formatFolded(stacks), 0o644); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if err := os.WriteFile(*out+".svg", flameSVG(stacks, unit, *title), 0o644); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Printf("%s.folded\n%s.svg\n", *out, *out)
+	return 0
+}
+
+// Stack, root first, to its total value.
+type foldedStacks map[string]int64
+
+func (f foldedStacks) add(frames []string, v int64) {
+	f[strings.Join(frames, ";")] += v
+}
+
+func formatFolded(f foldedStacks) []byte {
+	keys := make([]string, 0, len(f))
+	for k := range f {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	var b bytes.Buffer
+	for _, k := range keys {
+		fmt.Fprintf(&b, "%s %d\n", k, f[k])
+	}
+	return b.Bytes()
+}
+
+// Picks the parser from the file's magic: pprof profiles are gzipped,
+// perf.data starts with PERFILE2, anything else is taken to be perf
+// script output already. Returns the stacks and the unit of the values.
+func foldProfile(path string) (foldedStacks, string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, "", err
+	}
+	defer f.Close()
+	magic := make([]byte, 8)
+	n, _ := io.ReadFull(f, magic)
+	magic = magic[:n]
+
+	switch {
+	case bytes.HasPrefix(magic, []byte{0x1f, 0x8b}):
+		return foldPprof(path)
+	case bytes.HasPrefix(magic, []byte("PERFILE2")):
+		cmd := exec.Command("perf", "script", "-i", path)
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+		script, err := cmd.Output()
+		if err != nil {
+			return nil, "", fmt.Errorf("perf script -i %s: %v\n%s", path, err, stderr.String())
+		}
+		stacks, err := foldPerfScript(bytes.NewReader(script))
+		return stacks, "samples", err
+	default:
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return nil, "", err
+		}
+		stacks, err := foldPerfScript(f)
+		return stacks, "samples", err
+	}
+}
+
+// Go has no pprof reader outside x/ and we build without a module, so
+// let go tool pprof print the stacks:
+//
+//	-----------+-------------------------------------------------------
+//	      10ms   main.initSieve (inline)
+//	             main.runSieve
+//	             main.main
+//	             runtime.main
+//	-----------+-------------------------------------------------------
+func foldPprof(path string) (foldedStacks, string, error) {
+	cmd := exec.Command("go", "tool", "pprof", "-traces", path)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	traces, err := cmd.Output()
+	if err != nil {
+		return nil, "", fmt.Errorf("go tool pprof -traces %s: %v\n%s", path, err, stderr.String())
+	}
+	return foldPprofTraces(bytes.NewReader(traces), path)
+}
+
+// Folds the -traces output of the profile name.
+func foldPprofTraces(r io.Reader, name string) (foldedStacks, string, error) {
+	stacks := foldedStacks{}
+	unit := ""
+	var frames []string // leaf first, as pprof prints them
+	var value int64
+	flush := func() {
+		if len(frames) > 0 {
+			slices.Reverse(frames)
+			stacks.add(frames, value)
+		}
+		frames = frames[:0]
+	}
+	sc := bufio.NewScanner(r)
+	inTraces := false
+	for sc.Scan() {
+		line := sc.Text()
+		if strings.HasPrefix(line, "-----------+") {
+			flush()
+			inTraces = true
+			continue
+		}
+		if !inTraces {
+			continue
+		}
+		// Values are right-aligned in the first 11 columns, frames after
+		frame := strings.TrimLeft(line, " ")
+		if len(line)-len(frame) < 11 {
+			v, rest, _ := strings.Cut(frame, " ")
+			var err error
+			if value, unit, err = parsePprofValue(v); err != nil {
+				return nil, "", fmt.Errorf("%s: %v", name, err)
+			}
+			frame = strings.TrimSpace(rest)
+		}
+		if frame == "" {
+			continue
+		}
+		frames = append(frames, strings.TrimSuffix(frame, " (inline)"))
+	}
+	flush()
+	return stacks, unit, sc.Err()
+}
+
+var byteUnits = map[string]int64{"B": 1, "kB": 1 << 10, "MB": 1 << 20, "GB": 1 << 30, "TB": 1 << 40}
+
+// pprof prints durations (CPU), byte sizes (heap) or plain counts.
+func parsePprofValue(s string) (int64, string, error) {
+	if d, err := time.ParseDuration(s); err == nil {
+		return d.Nanoseconds(), "ns", nil
+	}
+	for suffix, scale := range byteUnits {
+		if num, ok := strings.CutSuffix(s, suffix); ok {
+			if f, err := strconv.ParseFloat(num, 64); err == nil {
+				return int64(f * float64(scale)), "bytes", nil
+			}
+		}
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, "", fmt.Errorf("can't parse sample value %q", s)
+	}
+	return n, "samples", nil
+}
+
+var (
+	perfPid    = regexp.MustCompile(`^\d+(/\d+)?$`)
+	perfOffset = regexp.MustCompile(`\+0x[0-9a-f]+$`)
+)
+
+// perf script prints one block per sample: a header naming the command,
+// then one frame per line, leaf first, then a blank line.
+//
+//	sieve-mml 4321 12345.678901:   250000 cycles:u:
+//		    401136 sieve+0x36 (/home/me/bin/sieve-mml)
+//		    4011f2 main+0x22 (/home/me/bin/sieve-mml)
+//
+// Each sample counts once, with the command as the root frame, the same
+// as stackcollapse-perf.pl.
+func foldPerfScript(r io.Reader) (foldedStacks, error) {
+	stacks := foldedStacks{}
+	var frames []string
+	comm := ""
+	flush := func() {
+		if comm != "" {
+			slices.Reverse(frames)
+			stacks.add(append([]string{comm}, frames...), 1)
+		}
+		comm, frames = "", frames[:0]
+	}
+	sc := bufio.NewScanner(r)
+	sc.Buffer(nil, 1<<20)
+	for sc.Scan() {
+		line := sc.Text()
+		switch {
+		case strings.TrimSpace(line) == "":
+			flush()
+		case line[0] != ' ' && line[0] != '\t':
+			flush()
+			fields := strings.Fields(line)
+			comm = fields[0]
+			for i, f := range fields {
+				if i > 0 && perfPid.MatchString(f) {
+					comm = strings.Join(fields[:i], " ")
+					break
+				}
+			}
+		default:
+			// address, symbol+offset, (dso)
+			fields := strings.Fields(line)
+			if len(fields) < 2 {
+				continue
+			}
+			sym := strings.Join(fields[1:len(fields)-1], " ")
+			if sym == "" {
+				sym = fields[1]
+			}
+			frames = append(frames, perfOffset.ReplaceAllString(sym, ""))
+		}
+	}
+	flush()
+	return stacks, sc.Err()
+}
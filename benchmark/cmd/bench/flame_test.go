@@ -0,0 +1,171 @@
+package main
+
+import (
+	"maps"
+	"strings"
+	"testing"
+)
+
+func TestFoldPerfScript(t *testing.T) {
+	for _, tc := range []struct {
+		name, in string
+		want     foldedStacks
+	}{
+		{name: "empty", in: "", want: foldedStacks{}},
+		{name: "one sample", in: `sieve-mml 4321 12345.678901:   250000 cycles:u:
+	    401136 sieve+0x36 (/home/me/bin/sieve-mml)
+	    4011f2 main+0x22 (/home/me/bin/sieve-mml)
+
+`, want: foldedStacks{"sieve-mml;main;sieve": 1}},
+		// Same stack twice counts twice; no trailing blank line still
+		// flushes the last sample.
+		{name: "repeated", in: `sieve-mml 4321 1.0: 1 cycles:u:
+	    401136 sieve+0x36 (/bin/sieve-mml)
+
+sieve-mml 4321 2.0: 1 cycles:u:
+	    401136 sieve+0x40 (/bin/sieve-mml)
+sieve-mml 4321 3.0: 1 cycles:u:
+	    4011f2 main+0x22 (/bin/sieve-mml)`, want: foldedStacks{"sieve-mml;sieve": 2, "sieve-mml;main": 1}},
+		// Command names run up to the pid, with or without /tid.
+		{name: "command with spaces", in: `Web Content 1234/1240 5.0: 1 cycles:
+	    7f00 js::RunScript+0x10 (/usr/lib/libxul.so)
+`, want: foldedStacks{"Web Content;js::RunScript": 1}},
+		// Symbols may have spaces; unknown frames have no offset.
+		{name: "symbol with spaces", in: `lines-mml 99 1.0: 1 cycles:u:
+	    7f01 operator new(unsigned long)+0x1e (/usr/lib/libstdc++.so.6)
+	    7f02 [unknown] ([unknown])
+	    7f03 [unknown]
+`, want: foldedStacks{"lines-mml;[unknown];[unknown];operator new(unsigned long)": 1}},
+		{name: "no frames", in: "sieve-mml 4321 1.0: 1 cycles:u:\n\n", want: foldedStacks{"sieve-mml": 1}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := foldPerfScript(strings.NewReader(tc.in))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !maps.Equal(got, tc.want) {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+const pprofTraces = `File: sieve-go
+Type: cpu
+Time: Oct 17, 2026 at 10:00am (UTC)
+Duration: 1.20s, Total samples = 1.10s (91.67%)
+-----------+-------------------------------------------------------
+      10ms   main.initSieve (inline)
+             main.runSieve
+             main.main
+             runtime.main
+-----------+-------------------------------------------------------
+     1.08s   main.runSieve
+             main.main
+             runtime.main
+-----------+-------------------------------------------------------
+      10ms   main.initSieve (inline)
+             main.runSieve
+             main.main
+             runtime.main
+-----------+-------------------------------------------------------
+`
+
+func TestFoldPprofTraces(t *testing.T) {
+	for _, tc := range []struct {
+		name, in string
+		want     foldedStacks
+		unit     string
+		err      string // substring of the error, "" for none
+	}{
+		{name: "empty", in: "", want: foldedStacks{}},
+		{name: "header only", in: "File: sieve-go\nType: cpu\n", want: foldedStacks{}},
+		{name: "cpu", in: pprofTraces, unit: "ns", want: foldedStacks{
+			"runtime.main;main.main;main.runSieve;main.initSieve": 20_000_000,
+			"runtime.main;main.main;main.runSieve":                1_080_000_000,
+		}},
+		{name: "heap", in: `Type: inuse_space
+-----------+-------------------------------------------------------
+     1.50MB   main.alloc
+             main.main
+-----------+-------------------------------------------------------
+`, unit: "bytes", want: foldedStacks{"main.main;main.alloc": 1_572_864}},
+		{name: "bad value", in: `-----------+-------------------------------------------------------
+      lots   main.main
+`, err: `x.pprof: can't parse sample value "lots"`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, unit, err := foldPprofTraces(strings.NewReader(tc.in), "x.pprof")
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("got %v, %v; want error containing %q", got, err, tc.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if unit != tc.unit {
+				t.Errorf("unit %q, want %q", unit, tc.unit)
+			}
+			if !maps.Equal(got, tc.want) {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParsePprofValue(t *testing.T) {
+	for _, tc := range []struct {
+		in   string
+		want int64
+		unit string
+	}{
+		{"10ms", 10_000_000, "ns"},
+		{"1.08s", 1_080_000_000, "ns"},
+		{"250us", 250_000, "ns"},
+		{"512B", 512, "bytes"},
+		{"1.50MB", 1_572_864, "bytes"},
+		{"2kB", 2048, "bytes"},
+		{"3GB", 3 << 30, "bytes"},
+		{"42", 42, "samples"},
+	} {
+		got, unit, err := parsePprofValue(tc.in)
+		if err != nil {
+			t.Errorf("%s: %v", tc.in, err)
+			continue
+		}
+		if got != tc.want || unit != tc.unit {
+			t.Errorf("%s: got %d %s, want %d %s", tc.in, got, unit, tc.want, tc.unit)
+		}
+	}
+	for _, in := range []string{"", "lots", "1.5", "MB", "10 ms"} {
+		if v, unit, err := parsePprofValue(in); err == nil {
+			t.Errorf("%q: got %d %s, want an error", in, v, unit)
+		}
+	}
+}
+
+// Sorted by stack, so the output is the same on every run.
+func TestFormatFolded(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		in   foldedStacks
+		want string
+	}{
+		{"empty", foldedStacks{}, ""},
+		{"sorted", foldedStacks{"main;b": 2, "main": 5, "main;a;x": 1}, "main 5\nmain;a;x 1\nmain;b 2\n"},
+	} {
+		if got := string(formatFolded(tc.in)); got != tc.want {
+			t.Errorf("%s: got %q, want %q", tc.name, got, tc.want)
+		}
+	}
+
+	f := foldedStacks{}
+	f.add([]string{"main", "sieve"}, 3)
+	f.add([]string{"main", "sieve"}, 4)
+	f.add([]string{"main"}, 1)
+	if got, want := string(formatFolded(f)), "main 1\nmain;sieve 7\n"; got != want {
+		t.Errorf("add: got %q, want %q", got, want)
+	}
+}
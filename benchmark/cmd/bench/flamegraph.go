@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"hash/fnv"
+	"html"
+	"slices"
+	"strings"
+)
+
+// A flame graph in plain SVG, in the style of flamegraph.pl: root at the
+// bottom, each frame as wide as its share of the samples, children
+// sorted by name, hover for the numbers. No scripts, so it opens fine
+// from CI artifacts.
+
+const (
+	flameWidth   = 1200
+	flameFrame   = 16
+	flameMargin  = 10
+	flameTitle   = 24
+	flameCharPx  = 7 // rough width of a 12px monospace character
+	flameMinPx   = 0.1
+	flameLabelPx = 3 * flameCharPx
+)
+
+type flameNode struct {
+	name     string
+	value    int64
+	children map[string]*flameNode
+}
+
+func buildFlameTree(stacks foldedStacks) (*flameNode, int) {
+	root := &flameNode{name: "all", children: map[string]*flameNode{}}
+	depth := 0
+	for stack, v := range stacks {
+		n := root
+		n.value += v
+		frames := strings.Split(stack, ";")
+		depth = max(depth, len(frames))
+		for _, name := range frames {
+			c, ok := n.children[name]
+			if !ok {
+				c = &flameNode{name: name, children: map[string]*flameNode{}}
+				n.children[name] = c
+			}
+			c.value += v
+			n = c
+		}
+	}
+	return root, depth + 1
+}
+
+func flameSVG(stacks foldedStacks, unit, title string) []byte {
+	root, depth := buildFlameTree(stacks)
+	height := flameTitle + depth*flameFrame + 2*flameMargin
+	scale := float64(flameWidth-2*flameMargin) / float64(root.value)
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, `<?xml version="1.0" standalone="no"?>
+<svg version="1.1" width="%d" height="%d" xmlns="http://www.w3.org/2000/svg" font-family="monospace" font-size="12">
+<rect width="100%%" height="100%%" fill="#f8f8f8"/>
+<text x="%d" y="%d" font-size="16" text-anchor="middle">%s</text>
+`, flameWidth, height, flameWidth/2, flameMargin+12, html.EscapeString(title))
+
+	var draw func(n *flameNode, x float64, level int)
+	draw = func(n *flameNode, x float64, level int) {
+		w := float64(n.value) * scale
+		if w < flameMinPx {
+			return
+		}
+		y := height - flameMargin - (level+1)*flameFrame
+		name := html.EscapeString(n.name)
+		fmt.Fprintf(&b, `<g><title>%s (%d %s, %.2f%%)</title><rect x="%.1f" y="%d" width="%.1f" height="%d" fill="%s" rx="2"/>`,
+			name, n.value, unit, 100*float64(n.value)/float64(root.value), x, y, w, flameFrame-1, flameColor(n.name))
+		if w > flameLabelPx {
+			label := n.name
+			if fit := int(w-6) / flameCharPx; len(label) > fit {
+				label = label[:max(fit-2, 0)] + ".."
+			}
+			fmt.Fprintf(&b, `<text x="%.1f" y="%d">%s</text>`, x+3, y+flameFrame-4, html.EscapeString(label))
+		}
+		b.WriteString("</g>\n")
+
+		names := make([]string, 0, len(n.children))
+		for name := range n.children {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		for _, name := range names {
+			c := n.children[name]
+			draw(c, x, level+1)
+			x += float64(c.value) * scale
+		}
+	}
+	draw(root, flameMargin, 0)
+	b.WriteString("</svg>\n")
+	return b.Bytes()
+}
+
+// flamegraph.pl's "hot" palette, seeded by the name so a function keeps
+// its colour across graphs.
+func flameColor(name string) string {
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	v := h.Sum32()
+	r := 205 + v%50
+	g := (v >> 8) % 230
+	bl := (v >> 16) % 55
+	return fmt.Sprintf("rgb(%d,%d,%d)", r, g, bl)
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// bench geninput: write the input files of the input-driven benchmarks,
+//
+//	bench geninput -size large lines
+//
+// Every corpus is a pure function of kind, size and seed, so the Go and
+// MML binaries (and two machines) read the same bytes. Each file is
+// printed with its sha256 in sha256sum format, and bench run records the
+// hash of every input it feeds a benchmark next to the results.
+
+// An input corpus. count is in units (lines, say) per -size preset, in
+// suiteSizes order. A corpus goes here once a benchmark reads it.
+type corpus struct {
+	ext   string
+	unit  string
+	count [4]int64
+	gen   func(w *bufio.Writer, n int64, r *lcg)
+}
+
+var corpora = map[string]corpus{
+	"lines": {"txt", "lines", [4]int64{1_000_000, 10_000_000, 30_000_000, 100_000_000}, genLines},
+}
+
+// Which corpus each benchmark reads, given to it with -file. MML
+// builds read stdin instead; see input in config.go.
+var benchInputs = map[string]string{
+	"lines": "lines",
+}
+
+const defaultInputDir = "build/inputs"
+
+// The LCG from rng.go, which this command can't import: the lines
+// corpus has to match lines -gen byte for byte, so the two copies must
+// change in lockstep.
+type lcg struct {
+	state int64
+}
+
+func newLCG(seed int64) *lcg {
+	return &lcg{state: seed}
+}
+
+func (r *lcg) next() int64 {
+	r.state = (r.state * 1664525) + 1013904223
+	return r.state
+}
+
+func (r *lcg) below(n int64) int64 {
+	return int64((uint64(r.next()) >> 33) % uint64(n))
+}
+
+func runGeninput(args []string) int {
+	fs := flag.NewFlagSet("geninput", flag.ExitOnError)
+	size := fs.String("size", "small", "small, medium, large or huge")
+	dir := fs.String("dir", defaultInputDir, "where the files go")
+	out := fs.String("o", "", "write a single corpus to this file instead, - for stdout")
+	seed := fs.Int64("seed", 42, "LCG seed")
+	list := fs.Bool("list", false, "list the corpora and their sizes")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: bench geninput [flags] <benchmark or corpus>...")
+		fs.PrintDefaults()
+	}
+	// The names can come before the flags too
+	var names []string
+	for len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		names, args = append(names, args[0]), args[1:]
+	}
+	fs.Parse(args)
+	names = append(names, fs.Args()...)
+
+	if *list {
+		listCorpora(os.Stdout)
+		return 0
+	}
+	if len(names) == 0 {
+		fs.Usage()
+		return 2
+	}
+	if *out != "" && len(names) > 1 {
+		fmt.Fprintln(os.Stderr, "-o takes a single corpus")
+		return 2
+	}
+	if !slices.Contains(suiteSizes, *size) {
+		fmt.Fprintf(os.Stderr, "unknown size %q, want one of %v\n", *size, suiteSizes)
+		return 2
+	}
+
+	for _, name := range names {
+		kind, err := corpusFor(name)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		path := *out
+		if path == "" {
+			path = inputPath(*dir, kind, *size, *seed)
+		}
+		hash, err := writeCorpus(path, kind, *size, *seed)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		if path != "-" {
+			fmt.Printf("%s  %s\n", hash, path)
+		}
+	}
+	return 0
+}
+
+func listCorpora(w io.Writer) {
+	kinds := make([]string, 0, len(corpora))
+	for k := range corpora {
+		kinds = append(kinds, k)
+	}
+	sort.Strings(kinds)
+	for _, k := range kinds {
+		c := corpora[k]
+		var sizes []string
+		for i, s := range suiteSizes {
+			sizes = append(sizes, fmt.Sprintf("%s %d", s, c.count[i]))
+		}
+		var users []string
+		for b, kind := range benchInputs {
+			if kind == k {
+				users = append(users, b)
+			}
+		}
+		sort.Strings(users)
+		fmt.Fprintf(w, "%-6s %s: %s", k, c.unit, strings.Join(sizes, ", "))
+		if len(users) > 0 {
+			fmt.Fprintf(w, " (read by %s)", strings.Join(users, ", "))
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+// A benchmark name stands for the corpus it reads.
+func corpusFor(name string) (string, error) {
+	if kind, ok := benchInputs[name]; ok {
+		return kind, nil
+	}
+	if _, ok := corpora[name]; ok {
+		return name, nil
+	}
+	return "", fmt.Errorf("no input corpus for %q; bench geninput -list shows them", name)
+}
+
+// The seed only shows in the name when it isn't the default, so the
+// usual files keep short names.
+func inputPath(dir, kind, size string, seed int64) string {
+	name := kind + "-" + size
+	if seed != 42 {
+		name += "-seed" + strconv.FormatInt(seed, 10)
+	}
+	return filepath.Join(dir, name+"."+corpora[kind].ext)
+}
+
+// Writes the corpus to path (- for stdout) and returns its sha256.
+func writeCorpus(path, kind, size string, seed int64) (string, error) {
+	c := corpora[kind]
+	n := c.count[slices.Index(suiteSizes, size)]
+	var f *os.File
+	if path == "-" {
+		f = os.Stdout
+	} else {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return "", err
+		}
+		var err error
+		if f, err = os.Create(path); err != nil {
+			return "", err
+		}
+	}
+	h := sha256.New()
+	w := bufio.NewWriterSize(io.MultiWriter(f, h), 64<<10)
+	c.gen(w, n, newLCG(seed))
+	err := w.Flush()
+	if f != os.Stdout {
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+	}
+	if err != nil {
+		return "", fmt.Errorf("%s: %v", path, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Returns the input file for kind and size, generating it if it isn't
+// there yet, and the sha256 of what's actually on disk.
+func ensureInput(dir, kind, size string) (path, hash string, err error) {
+	if !slices.Contains(suiteSizes, size) {
+		return "", "", fmt.Errorf("unknown size %q, want one of %v", size, suiteSizes)
+	}
+	path = inputPath(dir, kind, size, 42)
+	if _, err := os.Stat(path); err != nil {
+		fmt.Fprintf(os.Stderr, "generating %s...\n", path)
+		hash, err = writeCorpus(path, kind, size, 42)
+		return path, hash, err
+	}
+	hash, err = hashFile(path)
+	return path, hash, err
+}
+
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Write errors stick in the bufio.Writer and come out of Flush, so the
+// generators don't check them.
+
+// Signed integers, one per line: what lines -gen writes.
+func genLines(w *bufio.Writer, n int64, r *lcg) {
+	buf := make([]byte, 0, 24)
+	for i := int64(0); i < n; i++ {
+		buf = strconv.AppendInt(buf[:0], r.below(1<<30)-1<<29, 10)
+		buf = append(buf, '\n')
+		w.Write(buf)
+	}
+}
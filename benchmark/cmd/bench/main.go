@@ -0,0 +1,58 @@
+// Bench drives the Go benchmarks: it builds them, runs them and reports
+// on the results. Build it from the benchmark directory with
+//
+//	go build -o bin/bench cmd/bench/*.go
+//
+// and run it from there too, e.g. bin/bench pgo -filter sieve.
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+)
+
+type command struct {
+	summary string
+	run     func(args []string) int
+}
+
+var commands = map[string]command{
+	"compare":  {"run commands on the same workload and compare against the first", runCompare},
+	"flame":    {"fold a pprof or perf profile and draw a flame graph", runFlame},
+	"geninput": {"write the deterministic input files of the input-driven benchmarks", runGeninput},
+	"list":     {"show the benchmarks a -tags/-filter selection picks, with their metadata", runList},
+	"pgo":      {"profile each benchmark, rebuild with -pgo and compare", runPGO},
+	"run":      {"median kernel and wall time per benchmark, optionally RAPL energy", runRun},
+	"scale":    {"sweep GOMAXPROCS over the parallel variants and show scaling", runScale},
+	"size":     {"stripped binary size and -noop startup time next to kernel time", runSize},
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: bench <command> [flags]")
+	fmt.Fprintln(os.Stderr)
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", name, commands[name].summary)
+	}
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Run bench <command> -h for its flags.")
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", os.Args[1])
+		usage()
+		os.Exit(2)
+	}
+	os.Exit(cmd.run(os.Args[2:]))
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// What each benchmark is, from benchmarks.toml in the benchmark
+// directory: a category, free-form tags, and the runtime and peak
+// memory to expect at -size large. Selections like "everything quick"
+// or "the parallel fp ones" are flags on that, not lists in code.
+
+const metaFile = "benchmarks.toml"
+
+type benchMeta struct {
+	category string
+	tags     []string
+	runtime  time.Duration // 0: unknown
+	memory   int64         // bytes, 0: unknown
+}
+
+// Has every one of tags; the category counts as a tag.
+func (m benchMeta) hasTags(tags []string) bool {
+	for _, t := range tags {
+		if t != m.category && !slices.Contains(m.tags, t) {
+			return false
+		}
+	}
+	return true
+}
+
+// A missing file is no metadata rather than an error, so directories
+// without one still work as long as nothing selects on it.
+func loadMeta(dir string) (map[string]benchMeta, error) {
+	path := filepath.Join(dir, metaFile)
+	src, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return map[string]benchMeta{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	doc, err := parseTOML(string(src))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	meta := map[string]benchMeta{}
+	for name, v := range doc {
+		t, ok := v.(tomlTable)
+		if !ok {
+			return nil, fmt.Errorf("%s: %s should be a [%s] table", path, name, name)
+		}
+		d := tomlDecoder{t: t, where: name}
+		m := benchMeta{category: d.string("category"), tags: d.strings("tags")}
+		runtime, memory := d.string("runtime"), d.string("memory")
+		if err := d.finish(); err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+		if runtime != "" {
+			if m.runtime, err = time.ParseDuration(runtime); err != nil {
+				return nil, fmt.Errorf("%s: %s: runtime: %v", path, name, err)
+			}
+		}
+		if memory != "" {
+			if m.memory, err = parseBytes(memory); err != nil {
+				return nil, fmt.Errorf("%s: %s: memory: %v", path, name, err)
+			}
+		}
+		meta[name] = m
+	}
+	return meta, nil
+}
+
+// Sizes like 512KB, 384MB or 2GB, in powers of 1024 like RSS is.
+func parseBytes(s string) (int64, error) {
+	units := []struct {
+		suffix string
+		scale  int64
+	}{{"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10}, {"B", 1}}
+	for _, u := range units {
+		if num, ok := strings.CutSuffix(s, u.suffix); ok {
+			f, err := strconv.ParseFloat(strings.TrimSpace(num), 64)
+			if err != nil || f < 0 {
+				return 0, fmt.Errorf("bad size %q", s)
+			}
+			return int64(f * float64(u.scale)), nil
+		}
+	}
+	return 0, fmt.Errorf("bad size %q, want a number with B, KB, MB or GB", s)
+}
+
+func formatBytes(n int64) string {
+	switch {
+	case n >= 1<<30:
+		return fmt.Sprintf("%.1fGB", float64(n)/(1<<30))
+	case n >= 1<<20:
+		return fmt.Sprintf("%dMB", n>>20)
+	case n >= 1<<10:
+		return fmt.Sprintf("%dKB", n>>10)
+	}
+	return fmt.Sprintf("%dB", n)
+}
+
+// The flags that pick benchmarks, shared by run and list.
+type selection struct {
+	filter, skip string
+	tags         string
+	maxRuntime   time.Duration
+	maxMemory    string
+}
+
+func (s *selection) register(fs *flag.FlagSet) {
+	fs.StringVar(&s.filter, "filter", "", "only benchmarks matching this regexp")
+	fs.StringVar(&s.skip, "skip", "", "skip benchmarks matching this regexp")
+	fs.StringVar(&s.tags, "tags", "", "only benchmarks with all these comma-separated tags or categories (see "+metaFile+")")
+	fs.DurationVar(&s.maxRuntime, "max-runtime", 0, "only benchmarks expected to take at most this long at -size large")
+	fs.StringVar(&s.maxMemory, "max-memory", "", "only benchmarks expected to need at most this much memory at -size large, e.g. 512MB")
+}
+
+// Whether anything beyond the name patterns is asked for.
+func (s *selection) usesMeta() bool {
+	return s.tags != "" || s.maxRuntime > 0 || s.maxMemory != ""
+}
+
+// The benchmarks s picks out of all. Without an estimate a benchmark
+// passes -max-runtime and -max-memory, but never -tags.
+func (s *selection) apply(all []benchmark, meta map[string]benchMeta) ([]benchmark, error) {
+	benches, err := selectBenchmarks(all, s.filter, s.skip)
+	if err != nil {
+		return nil, err
+	}
+	if !s.usesMeta() {
+		return benches, nil
+	}
+	var tags []string
+	for _, t := range strings.Split(s.tags, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			tags = append(tags, t)
+		}
+	}
+	var maxMemory int64
+	if s.maxMemory != "" {
+		if maxMemory, err = parseBytes(s.maxMemory); err != nil {
+			return nil, fmt.Errorf("-max-memory: %v", err)
+		}
+	}
+	var out []benchmark
+	for _, b := range benches {
+		m := meta[b.name]
+		if !m.hasTags(tags) ||
+			s.maxRuntime > 0 && m.runtime > s.maxRuntime ||
+			maxMemory > 0 && m.memory > maxMemory {
+			continue
+		}
+		out = append(out, b)
+	}
+	return out, nil
+}
+
+// bench list: the benchmarks a selection picks, with their metadata,
+// to check a -tags or -max-runtime before a long run.
+func runList(args []string) int {
+	fs := flag.NewFlagSet("list", flag.ExitOnError)
+	dir := fs.String("dir", ".", "benchmark directory")
+	var sel selection
+	sel.register(fs)
+	fs.Parse(args)
+
+	all, _, err := discover(*dir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	meta, err := loadMeta(*dir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	benches, err := sel.apply(all, meta)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	var total time.Duration
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "benchmark\tcategory\ttags\truntime\tmemory")
+	for _, b := range benches {
+		m, ok := meta[b.name]
+		if !ok {
+			fmt.Fprintf(tw, "%s\t-\t-\t?\t?\n", b.name)
+			continue
+		}
+		runtime, memory := "?", "?"
+		if m.runtime > 0 {
+			runtime = m.runtime.String()
+			total += m.runtime
+		}
+		if m.memory > 0 {
+			memory = formatBytes(m.memory)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", b.name, m.category, strings.Join(m.tags, ","), runtime, memory)
+	}
+	tw.Flush()
+	fmt.Printf("%d benchmarks, about %v a run at -size large\n", len(benches), total.Round(time.Second))
+
+	// Stale entries would silently never match
+	var orphans []string
+	for name := range meta {
+		if !slices.ContainsFunc(all, func(b benchmark) bool { return b.name == name }) {
+			orphans = append(orphans, name)
+		}
+	}
+	if len(orphans) > 0 {
+		sort.Strings(orphans)
+		fmt.Fprintf(os.Stderr, "%s has entries for missing benchmarks: %s\n", metaFile, strings.Join(orphans, ", "))
+	}
+	return 0
+}
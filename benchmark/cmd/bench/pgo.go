@@ -0,0 +1,102 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// bench pgo: for each benchmark, build normally and time it, run once
+// more with -cpuprofile, rebuild with -pgo=<that profile> and time the
+// result. PGO-enabled Go is the fairer baseline against LLVM at -O2/-O3.
+func runPGO(args []string) int {
+	fs := flag.NewFlagSet("pgo", flag.ExitOnError)
+	dir := fs.String("dir", ".", "benchmark directory")
+	outDir := fs.String("o", "build/pgo", "where builds and profiles go")
+	filter := fs.String("filter", "", "only benchmarks matching this regexp")
+	skip := fs.String("skip", "^lines$", "skip benchmarks matching this regexp (lines reads stdin)")
+	runs := fs.Int("runs", 5, "timed runs per build; the median is reported (under 5 can't reach p<0.05)")
+	benchArgs := fs.String("args", "-size small", "arguments passed to every benchmark")
+	profileArgs := fs.String("profile-args", "", "arguments for the profiling run (default: same as -args)")
+	alpha := fs.Float64("alpha", 0.05, "significance level; larger p-values print as ~")
+	fs.Parse(args)
+
+	if *runs < 1 {
+		fmt.Fprintln(os.Stderr, "-runs must be at least 1")
+		return 2
+	}
+	if *profileArgs == "" {
+		*profileArgs = *benchArgs
+	}
+
+	all, shared, err := discover(*dir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	benches, err := selectBenchmarks(all, *filter, *skip)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	if err := os.MkdirAll(*outDir, 0o755); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	runArgs := strings.Fields(*benchArgs)
+
+	fmt.Println(describeHost())
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "benchmark\tdefault\tpgo\tdelta")
+	status := 0
+	for _, b := range benches {
+		fmt.Fprintf(os.Stderr, "%s...\n", b.name)
+		base, withPGO, err := pgoOne(b, shared, *outDir, *runs, runArgs, strings.Fields(*profileArgs))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			fmt.Fprintf(tw, "%s\tfailed\n", b.name)
+			status = 1
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t%v\t%v\t%s\n", b.name, median(base).Round(time.Microsecond),
+			median(withPGO).Round(time.Microsecond), significance(withPGO, base, *alpha))
+	}
+	tw.Flush()
+	return status
+}
+
+// Kernel times without and with PGO.
+func pgoOne(b benchmark, shared []string, outDir string, runs int, runArgs, profileArgs []string) ([]time.Duration, []time.Duration, error) {
+	bin := filepath.Join(outDir, b.name)
+	profile := filepath.Join(outDir, b.name+".pprof")
+	pgoBin := filepath.Join(outDir, b.name+"-pgo")
+
+	if err := build(b, shared, bin); err != nil {
+		return nil, nil, err
+	}
+	base, err := measureSamples(bin, runArgs, runs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if _, err := runKernel(bin, append([]string{"-cpuprofile", profile}, profileArgs...)); err != nil {
+		return nil, nil, err
+	}
+	// -pgo wants an absolute path or one relative to the main package
+	abs, err := filepath.Abs(profile)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := build(b, shared, pgoBin, "-pgo="+abs); err != nil {
+		return nil, nil, err
+	}
+	withPGO, err := measureSamples(pgoBin, runArgs, runs)
+	if err != nil {
+		return nil, nil, err
+	}
+	return base, withPGO, nil
+}
@@ -0,0 +1,312 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"runtime"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// What bench run measured, and the ways it can print it.
+type runReport struct {
+	host    string // describeHost line
+	noise   noise
+	args    string
+	energy  bool
+	mem     bool
+	started time.Time
+	results []runResult
+	inputs  map[string]string // input file -> sha256
+}
+
+// Sorted, so reports diff cleanly.
+func (rep runReport) inputFiles() []string {
+	files := make([]string, 0, len(rep.inputs))
+	for f := range rep.inputs {
+		files = append(files, f)
+	}
+	slices.Sort(files)
+	return files
+}
+
+func writeTable(w io.Writer, rep runReport) {
+	fmt.Fprintln(w, rep.host)
+	fmt.Fprintf(w, "# noise: %v\n", rep.noise)
+	for _, f := range rep.inputFiles() {
+		fmt.Fprintf(w, "# input: %s sha256 %s\n", f, rep.inputs[f])
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprint(tw, "benchmark\tkernel p50\tp90\tp99\twall")
+	if rep.mem {
+		fmt.Fprint(tw, "\tstack peak\theap peak")
+	}
+	if rep.energy {
+		fmt.Fprint(tw, "\tpackage J\tdram J")
+	}
+	fmt.Fprintln(tw)
+	for _, r := range rep.results {
+		if r.failed {
+			fmt.Fprintf(tw, "%s\tfailed\n", r.name)
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t%v\t%v\t%v\t%v", r.name, r.kernel.Round(time.Microsecond),
+			percentile(r.kernels, 90).Round(time.Microsecond), percentile(r.kernels, 99).Round(time.Microsecond),
+			r.wall.Round(time.Microsecond))
+		if rep.mem {
+			if r.hasMem {
+				fmt.Fprintf(tw, "\t%s\t%s", formatBytes(r.stackPeak), formatBytes(r.heapPeak))
+			} else {
+				fmt.Fprint(tw, "\t-\t-")
+			}
+		}
+		if rep.energy {
+			dram := "-"
+			if r.energy.hasDRAM {
+				dram = fmt.Sprintf("%.3f", r.energy.dram)
+			}
+			fmt.Fprintf(tw, "\t%.3f\t%s", r.energy.pkg, dram)
+		}
+		fmt.Fprintln(tw)
+	}
+	tw.Flush()
+
+	var wall, checked []string
+	for _, r := range rep.results {
+		if r.failed {
+			continue
+		}
+		if !r.selfTimed {
+			wall = append(wall, r.name)
+		}
+		if r.checked {
+			checked = append(checked, r.name)
+		}
+	}
+	if len(wall) > 0 {
+		fmt.Fprintf(w, "# kernel is wall clock, no MMLBENCH line: %s\n", strings.Join(wall, ", "))
+	}
+	if len(checked) > 0 {
+		fmt.Fprintf(w, "# output checked against expected.go: %s\n", strings.Join(checked, ", "))
+	}
+}
+
+const histBins = 10
+
+// Kernel times of each benchmark in histBins equal-width bins from the
+// fastest run to the slowest, to show tails and bimodal runs that the
+// percentiles only hint at.
+func writeHistograms(w io.Writer, rep runReport) {
+	for _, r := range rep.results {
+		if r.failed || len(r.kernels) < 2 {
+			continue
+		}
+		lo, hi := slices.Min(r.kernels), slices.Max(r.kernels)
+		fmt.Fprintf(w, "\n%s (%d runs)\n", r.name, len(r.kernels))
+		if lo == hi {
+			fmt.Fprintf(w, "  %12v  %s %d\n", lo.Round(time.Microsecond), strings.Repeat("#", 40), len(r.kernels))
+			continue
+		}
+		var counts [histBins]int
+		width := float64(hi-lo) / histBins
+		for _, k := range r.kernels {
+			counts[min(int(float64(k-lo)/width), histBins-1)]++
+		}
+		most := slices.Max(counts[:])
+		for i, c := range counts {
+			from := lo + time.Duration(float64(i)*width)
+			fmt.Fprintf(w, "  %12v  %-40s %d\n", from.Round(time.Microsecond), strings.Repeat("#", c*40/most), c)
+		}
+	}
+}
+
+// The JSON shape of a run. Durations are integer nanoseconds.
+type jsonReport struct {
+	Host    string            `json:"host"`
+	Args    string            `json:"args"`
+	Started time.Time         `json:"started"`
+	NoiseCV float64           `json:"noise_cv"`
+	Inputs  map[string]string `json:"inputs,omitempty"`
+	Results []jsonResult      `json:"results"`
+}
+
+type jsonResult struct {
+	Name      string   `json:"name"`
+	Failed    bool     `json:"failed,omitempty"`
+	Input     string   `json:"input_sha256,omitempty"`
+	SelfTimed bool     `json:"self_timed"`
+	Checked   bool     `json:"checked,omitempty"`
+	KernelP50 int64    `json:"kernel_p50_ns,omitempty"`
+	KernelP90 int64    `json:"kernel_p90_ns,omitempty"`
+	KernelP99 int64    `json:"kernel_p99_ns,omitempty"`
+	WallP50   int64    `json:"wall_p50_ns,omitempty"`
+	KernelNs  []int64  `json:"kernel_ns,omitempty"`
+	WallNs    []int64  `json:"wall_ns,omitempty"`
+	StackPeak *int64   `json:"stack_peak_bytes,omitempty"`
+	HeapPeak  *int64   `json:"heap_peak_bytes,omitempty"`
+	PackageJ  *float64 `json:"package_j,omitempty"`
+	DRAMJ     *float64 `json:"dram_j,omitempty"`
+}
+
+func nanos(ds []time.Duration) []int64 {
+	ns := make([]int64, len(ds))
+	for i, d := range ds {
+		ns[i] = d.Nanoseconds()
+	}
+	return ns
+}
+
+func writeJSON(w io.Writer, rep runReport) error {
+	out := jsonReport{
+		Host:    strings.TrimPrefix(rep.host, "# "),
+		Args:    rep.args,
+		Started: rep.started,
+		NoiseCV: rep.noise.cv,
+		Inputs:  rep.inputs,
+		Results: []jsonResult{},
+	}
+	for _, r := range rep.results {
+		jr := jsonResult{Name: r.name, Failed: r.failed, Input: r.inputHash, SelfTimed: r.selfTimed, Checked: r.checked}
+		if !r.failed {
+			jr.KernelP50 = r.kernel.Nanoseconds()
+			jr.KernelP90 = percentile(r.kernels, 90).Nanoseconds()
+			jr.KernelP99 = percentile(r.kernels, 99).Nanoseconds()
+			jr.WallP50 = r.wall.Nanoseconds()
+			jr.KernelNs = nanos(r.kernels)
+			jr.WallNs = nanos(r.walls)
+			if r.hasMem {
+				jr.StackPeak, jr.HeapPeak = &r.stackPeak, &r.heapPeak
+			}
+			if rep.energy {
+				jr.PackageJ = &r.energy.pkg
+				if r.energy.hasDRAM {
+					jr.DRAMJ = &r.energy.dram
+				}
+			}
+		}
+		out.Results = append(out.Results, jr)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
+func hostname() string {
+	h, err := os.Hostname()
+	if err != nil {
+		return "unknown"
+	}
+	return h
+}
+
+// InfluxDB line protocol escapes commas, spaces and equals signs in tag
+// values with a backslash, and backslashes themselves, or args ending in
+// one would escape the space before the fields.
+var influxTag = strings.NewReplacer(`\`, `\\`, ",", `\,`, " ", `\ `, "=", `\=`)
+
+// One mmlbench point per benchmark plus one mmlbench_noise point, all
+// stamped with the start of the run:
+//
+//	mmlbench,benchmark=sieve,host=ci-1,go=go1.27.1,args=-size\ small kernel_ns=7084000i,kernel_p90_ns=7410000i,kernel_p99_ns=7902000i,wall_ns=10034000i 1760659200000000000
+//
+// Benchmarks that read an input also get an input_sha256 string field.
+// Failed benchmarks are left out.
+func writeInflux(w io.Writer, rep runReport) {
+	tags := fmt.Sprintf("host=%s,go=%s,args=%s", influxTag.Replace(hostname()),
+		influxTag.Replace(runtime.Version()), influxTag.Replace(rep.args))
+	ts := rep.started.UnixNano()
+	fmt.Fprintf(w, "mmlbench_noise,%s cv=%g,calibration_ns=%di %d\n", tags, rep.noise.cv, rep.noise.mean.Nanoseconds(), ts)
+	for _, r := range rep.results {
+		if r.failed {
+			continue
+		}
+		fmt.Fprintf(w, "mmlbench,benchmark=%s,%s kernel_ns=%di,kernel_p90_ns=%di,kernel_p99_ns=%di,wall_ns=%di",
+			influxTag.Replace(r.name), tags, r.kernel.Nanoseconds(), percentile(r.kernels, 90).Nanoseconds(),
+			percentile(r.kernels, 99).Nanoseconds(), r.wall.Nanoseconds())
+		if r.hasMem {
+			fmt.Fprintf(w, ",stack_peak_bytes=%di,heap_peak_bytes=%di", r.stackPeak, r.heapPeak)
+		}
+		if r.inputHash != "" {
+			fmt.Fprintf(w, ",input_sha256=%q", r.inputHash)
+		}
+		if rep.energy {
+			fmt.Fprintf(w, ",package_j=%g", r.energy.pkg)
+			if r.energy.hasDRAM {
+				fmt.Fprintf(w, ",dram_j=%g", r.energy.dram)
+			}
+		}
+		fmt.Fprintf(w, " %d\n", ts)
+	}
+}
+
+// Prometheus text format. Label values escape backslash, quote and
+// newline; benchmark names never have them but args could.
+var promLabel = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func formatPrometheus(rep runReport) []byte {
+	var b bytes.Buffer
+	labels := func(name string) string {
+		return fmt.Sprintf(`benchmark="%s",go="%s",args="%s"`, promLabel.Replace(name),
+			promLabel.Replace(runtime.Version()), promLabel.Replace(rep.args))
+	}
+	gauge := func(metric, help string, each func(r runResult) (string, bool)) {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s gauge\n", metric, help, metric)
+		for _, r := range rep.results {
+			if r.failed {
+				continue
+			}
+			if v, ok := each(r); ok {
+				fmt.Fprintf(&b, "%s{%s} %s\n", metric, labels(r.name), v)
+			}
+		}
+	}
+	seconds := func(d time.Duration) string { return fmt.Sprintf("%g", d.Seconds()) }
+
+	gauge("mmlbench_kernel_seconds", "Median kernel time.", func(r runResult) (string, bool) {
+		return seconds(r.kernel), true
+	})
+	gauge("mmlbench_wall_seconds", "Median process wall time.", func(r runResult) (string, bool) {
+		return seconds(r.wall), true
+	})
+	if rep.energy {
+		gauge("mmlbench_package_joules", "Median RAPL package energy per run.", func(r runResult) (string, bool) {
+			return fmt.Sprintf("%g", r.energy.pkg), true
+		})
+		gauge("mmlbench_dram_joules", "Median RAPL DRAM energy per run.", func(r runResult) (string, bool) {
+			return fmt.Sprintf("%g", r.energy.dram), r.energy.hasDRAM
+		})
+	}
+	fmt.Fprintf(&b, "# HELP mmlbench_noise_cv Coefficient of variation of the calibration kernel.\n")
+	fmt.Fprintf(&b, "# TYPE mmlbench_noise_cv gauge\nmmlbench_noise_cv %g\n", rep.noise.cv)
+	return b.Bytes()
+}
+
+// PUT replaces every metric in the job/instance group, so benchmarks
+// that have been removed don't linger on the dashboard.
+func pushMetrics(gateway, job string, rep runReport) error {
+	u := strings.TrimSuffix(gateway, "/") + "/metrics/job/" + url.PathEscape(job) +
+		"/instance/" + url.PathEscape(hostname())
+	req, err := http.NewRequest(http.MethodPut, u, bytes.NewReader(formatPrometheus(rep)))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; version=0.0.4")
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("%s: %s: %s", u, resp.Status, bytes.TrimSpace(body))
+	}
+	return nil
+}
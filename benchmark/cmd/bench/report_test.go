@@ -0,0 +1,193 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// A report with a name and args that need escaping in both formats.
+func testReport() runReport {
+	ms := func(xs ...int) []time.Duration {
+		ds := make([]time.Duration, len(xs))
+		for i, x := range xs {
+			ds[i] = time.Duration(x) * time.Millisecond
+		}
+		return ds
+	}
+	return runReport{
+		args:    `-size small,x=1 "q" \`,
+		noise:   noise{mean: 2 * time.Millisecond, cv: 0.01},
+		started: time.Unix(1760659200, 0),
+		results: []runResult{
+			{name: "sieve", kernel: 7 * time.Millisecond, wall: 10 * time.Millisecond, kernels: ms(7, 7, 8), walls: ms(10, 10, 11)},
+			{name: "lines mml,a=b", kernel: 3 * time.Millisecond, wall: 4 * time.Millisecond, kernels: ms(3, 3, 3),
+				inputHash: "abc123", hasMem: true, stackPeak: 8192, heapPeak: 1 << 20},
+			{name: "broken", failed: true},
+		},
+	}
+}
+
+func TestWriteInflux(t *testing.T) {
+	var b bytes.Buffer
+	writeInflux(&b, testReport())
+	lines := strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("got %d lines, want the noise point and 2 benchmarks:\n%s", len(lines), b.String())
+	}
+
+	tags := `,host=` + influxTag.Replace(hostname()) + `,go=` + influxTag.Replace(runtime.Version()) +
+		`,args=-size\ small\,x\=1\ "q"\ \\`
+	for i, want := range []string{
+		"mmlbench_noise" + tags + " cv=0.01,calibration_ns=2000000i 1760659200000000000",
+		"mmlbench,benchmark=sieve" + tags +
+			" kernel_ns=7000000i,kernel_p90_ns=8000000i,kernel_p99_ns=8000000i,wall_ns=10000000i 1760659200000000000",
+		`mmlbench,benchmark=lines\ mml\,a\=b` + tags +
+			` kernel_ns=3000000i,kernel_p90_ns=3000000i,kernel_p99_ns=3000000i,wall_ns=4000000i` +
+			`,stack_peak_bytes=8192i,heap_peak_bytes=1048576i,input_sha256="abc123" 1760659200000000000`,
+	} {
+		if lines[i] != want {
+			t.Errorf("line %d:\n got %s\nwant %s", i+1, lines[i], want)
+		}
+	}
+
+	// Escaping leaves exactly three space-separated parts a line.
+	for _, line := range lines {
+		if parts := splitUnescaped(line, ' '); len(parts) != 3 {
+			t.Errorf("%d parts, want 3: %s", len(parts), line)
+		}
+	}
+}
+
+func TestWriteInfluxEnergy(t *testing.T) {
+	rep := testReport()
+	rep.energy = true
+	rep.results[0].energy = energy{pkg: 1.5, dram: 0.25, hasDRAM: true}
+	rep.results[1].energy = energy{pkg: 0.5}
+	var b bytes.Buffer
+	writeInflux(&b, rep)
+	lines := strings.Split(b.String(), "\n")
+	if !strings.Contains(lines[1], ",package_j=1.5,dram_j=0.25 ") {
+		t.Errorf("no package and dram fields: %s", lines[1])
+	}
+	if !strings.Contains(lines[2], ",package_j=0.5 ") || strings.Contains(lines[2], "dram_j") {
+		t.Errorf("want package but no dram field: %s", lines[2])
+	}
+}
+
+func TestInfluxTag(t *testing.T) {
+	for in, want := range map[string]string{
+		"":               "",
+		"sieve":          "sieve",
+		"-size small":    `-size\ small`,
+		"a,b=c":          `a\,b\=c`,
+		`C:\bin "x"`:     `C:\\bin\ "x"`,
+		`trailing \`:     `trailing\ \\`,
+		"sort merge,x=1": `sort\ merge\,x\=1`,
+	} {
+		if got := influxTag.Replace(in); got != want {
+			t.Errorf("%q: got %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestPromLabel(t *testing.T) {
+	for in, want := range map[string]string{
+		"":            "",
+		"-size small": "-size small",
+		`say "hi"`:    `say \"hi\"`,
+		`C:\bin`:      `C:\\bin`,
+		"a\nb":        `a\nb`,
+		`\"`:          `\\\"`,
+	} {
+		if got := promLabel.Replace(in); got != want {
+			t.Errorf("%q: got %q, want %q", in, got, want)
+		}
+	}
+}
+
+// Every sample line is metric{labels} value, with the labels' quotes
+// balanced however the args were written.
+var promSample = regexp.MustCompile(`^(mmlbench_[a-z_]+)(\{benchmark="(?:[^"\\\n]|\\.)*",go="(?:[^"\\\n]|\\.)*",args="(?:[^"\\\n]|\\.)*"\})? (\S+)$`)
+
+func TestFormatPrometheus(t *testing.T) {
+	rep := testReport()
+	rep.args += "\nsecond line"
+	rep.energy = true
+	rep.results[0].energy = energy{pkg: 1.5, dram: 0.25, hasDRAM: true}
+	rep.results[1].energy = energy{pkg: 0.5}
+	out := string(formatPrometheus(rep))
+
+	samples := map[string]int{}
+	for _, line := range strings.Split(strings.TrimSuffix(out, "\n"), "\n") {
+		if strings.HasPrefix(line, "# HELP ") || strings.HasPrefix(line, "# TYPE ") {
+			continue
+		}
+		m := promSample.FindStringSubmatch(line)
+		if m == nil {
+			t.Errorf("malformed sample: %q", line)
+			continue
+		}
+		samples[m[1]]++
+	}
+	// Failed benchmarks are left out, and dram only where it was read.
+	for metric, want := range map[string]int{
+		"mmlbench_kernel_seconds": 2, "mmlbench_wall_seconds": 2,
+		"mmlbench_package_joules": 2, "mmlbench_dram_joules": 1, "mmlbench_noise_cv": 1,
+	} {
+		if samples[metric] != want {
+			t.Errorf("%s: %d samples, want %d", metric, samples[metric], want)
+		}
+	}
+
+	want := `mmlbench_kernel_seconds{benchmark="lines mml,a=b",go="` + runtime.Version() +
+		`",args="-size small,x=1 \"q\" \\\nsecond line"} 0.003`
+	if !strings.Contains(out, want+"\n") {
+		t.Errorf("no line %s in\n%s", want, out)
+	}
+	if strings.Contains(out, "broken") {
+		t.Errorf("failed benchmark in output:\n%s", out)
+	}
+}
+
+func TestPushMetrics(t *testing.T) {
+	rep := testReport()
+	var method, path, contentType string
+	var body []byte
+	status := http.StatusOK
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method, path, contentType = r.Method, r.URL.EscapedPath(), r.Header.Get("Content-Type")
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(status)
+		io.WriteString(w, "push failed\n")
+	}))
+	defer srv.Close()
+
+	if err := pushMetrics(srv.URL+"/", "mml bench", rep); err != nil {
+		t.Fatal(err)
+	}
+	if method != http.MethodPut {
+		t.Errorf("method %s, want PUT", method)
+	}
+	if want := "/metrics/job/mml%20bench/instance/" + hostname(); path != want {
+		t.Errorf("path %s, want %s", path, want)
+	}
+	if !strings.HasPrefix(contentType, "text/plain") {
+		t.Errorf("content type %q", contentType)
+	}
+	if !bytes.Equal(body, formatPrometheus(rep)) {
+		t.Errorf("body differs from formatPrometheus:\n%s", body)
+	}
+
+	status = http.StatusBadRequest
+	err := pushMetrics(srv.URL, "bench", rep)
+	if err == nil || !strings.Contains(err.Error(), "400 Bad Request: push failed") {
+		t.Errorf("got %v, want the status and body", err)
+	}
+}
@@ -0,0 +1,413 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// bench run: build every benchmark and report the median kernel and wall
+// time over -runs, plus package and DRAM energy with -energy. A noise
+// calibration runs first and goes in the header; over -max-noise it's a
+// warning, or an error with -strict. -format=influx and -push feed
+// dashboards directly; -summary writes Markdown for CI job pages. With
+// -c the suite file picks what runs (see config.go). Kernel times are
+// reported as p50/p90/p99, since GC pauses and page faults live in the
+// tail; -format=json keeps every sample. Benchmarks that read an input
+// get it from -inputs (generated as needed, see geninput.go) at the size
+// in -args, and its sha256 goes in the report. -tags, -max-runtime and
+// -max-memory select on benchmarks.toml (see meta.go); with -c they only
+// apply to the suite's Go benchmarks. -mem adds each Go benchmark's peak
+// stack growth and heap use (see profile.go).
+func runRun(args []string) int {
+	fs := flag.NewFlagSet("run", flag.ExitOnError)
+	dir := fs.String("dir", ".", "benchmark directory")
+	outDir := fs.String("o", "build/run", "where builds go")
+	var sel selection
+	sel.register(fs)
+	inputDir := fs.String("inputs", defaultInputDir, "where input files are, or get generated")
+	runs := fs.Int("runs", 5, "runs per benchmark; medians are reported")
+	benchArgs := fs.String("args", "-size small", "arguments passed to every benchmark")
+	withMem := fs.Bool("mem", false, "also report peak stack growth and heap use of the Go benchmarks")
+	withEnergy := fs.Bool("energy", false, "measure package and DRAM energy per run through RAPL (Linux)")
+	powercap := fs.String("powercap", defaultPowercap, "powercap sysfs directory for -energy")
+	maxNoise := fs.Float64("max-noise", 0.03, "calibration coefficient of variation above which the machine counts as noisy")
+	strict := fs.Bool("strict", false, "refuse to run on a noisy machine instead of warning")
+	format := fs.String("format", "table", "table, json (with every raw sample) or influx for InfluxDB line protocol")
+	hist := fs.Bool("hist", false, "with -format=table, add a histogram of each benchmark's kernel times")
+	push := fs.String("push", "", "also push the results to this Prometheus pushgateway URL")
+	pushJob := fs.String("push-job", "mmlbench", "job label for -push")
+	summary := fs.String("summary", os.Getenv("GITHUB_STEP_SUMMARY"), "append a Markdown summary to this file (default $GITHUB_STEP_SUMMARY)")
+	baselinePath := fs.String("baseline", "", "previous -format=influx output to show regressions against")
+	configPath := fs.String("c", "", "run the suite in this TOML file instead of every benchmark with -args")
+	fs.Parse(args)
+
+	if *runs < 1 {
+		fmt.Fprintln(os.Stderr, "-runs must be at least 1")
+		return 2
+	}
+	if *format != "table" && *format != "influx" && *format != "json" {
+		fmt.Fprintf(os.Stderr, "unknown format %q\n", *format)
+		return 2
+	}
+	var baseline map[string]time.Duration
+	if *baselinePath != "" {
+		var err error
+		if baseline, err = readBaseline(*baselinePath); err != nil {
+			fmt.Fprintf(os.Stderr, "-baseline: %v\n", err)
+			return 2
+		}
+	}
+	var zones []raplZone
+	if *withEnergy {
+		var err error
+		if zones, err = findRAPL(*powercap); err != nil {
+			fmt.Fprintf(os.Stderr, "-energy: %v\n", err)
+			return 2
+		}
+	}
+
+	all, shared, err := discover(*dir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	meta, err := loadMeta(*dir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	var jobs []runJob
+	label := *benchArgs
+	if *configPath != "" {
+		c, err := loadSuite(*configPath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		expected, err := loadExpected(*dir)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		if jobs, err = suiteJobs(c, all, expected, *outDir, *inputDir, sel.filter); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", *configPath, err)
+			return 2
+		}
+		if sel.usesMeta() {
+			// -filter already went by row name
+			bySel := sel
+			bySel.filter, bySel.skip = "", ""
+			picked, err := bySel.apply(all, meta)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return 2
+			}
+			jobs = slices.DeleteFunc(jobs, func(j runJob) bool {
+				return j.bench.name != "" && !slices.Contains(picked, j.bench)
+			})
+		}
+		explicit := map[string]bool{}
+		fs.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+		if c.runs > 0 && !explicit["runs"] {
+			*runs = c.runs
+		}
+		label = "suite " + *configPath
+	} else {
+		benches, err := sel.apply(all, meta)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		for _, b := range benches {
+			j := runJob{name: b.name, bench: b, bin: filepath.Join(*outDir, b.name), args: strings.Fields(*benchArgs)}
+			if kind, ok := benchInputs[b.name]; ok {
+				// The input stands in for -size
+				size := "small"
+				if i := slices.Index(j.args, "-size"); i >= 0 && i+1 < len(j.args) {
+					size = j.args[i+1]
+					j.args = slices.Delete(j.args, i, i+2)
+				}
+				if !slices.Contains(suiteSizes, size) {
+					fmt.Fprintf(os.Stderr, "%s: unknown size %q, want one of %v\n", b.name, size, suiteSizes)
+					return 2
+				}
+				if err := j.useInput(*inputDir, kind, size, false); err != nil {
+					fmt.Fprintln(os.Stderr, err)
+					return 1
+				}
+			}
+			jobs = append(jobs, j)
+		}
+	}
+	if err := os.MkdirAll(*outDir, 0o755); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	n := calibrate()
+	if n.cv > *maxNoise {
+		if *strict {
+			fmt.Fprintf(os.Stderr, "machine too noisy: %v, over -max-noise %.2f%%\n", n, 100**maxNoise)
+			return 1
+		}
+		fmt.Fprintf(os.Stderr, "warning: machine is noisy: %v, over -max-noise %.2f%%\n", n, 100**maxNoise)
+	}
+
+	rep := runReport{host: describeHost(), noise: n, args: label, energy: zones != nil, mem: *withMem,
+		started: time.Now(), inputs: map[string]string{}}
+	for i, j := range jobs {
+		if j.input != "" {
+			rep.inputs[j.input] = j.inputHash
+		}
+		if *withMem && j.bench.name != "" {
+			jobs[i].args = append(slices.Clip(j.args), "-mem")
+		}
+	}
+	status := 0
+	built := map[string]error{}
+	for _, j := range jobs {
+		fmt.Fprintf(os.Stderr, "%s...\n", j.name)
+		if j.bench.name != "" {
+			err, done := built[j.bin]
+			if !done {
+				err = build(j.bench, shared, j.bin)
+				built[j.bin] = err
+			}
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				rep.results = append(rep.results, runResult{name: j.name, failed: true})
+				status = 1
+				continue
+			}
+		}
+		r, err := runMany(j, *runs, zones)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			r.failed = true
+			status = 1
+		}
+		r.name = j.name
+		r.inputHash = j.inputHash
+		rep.results = append(rep.results, r)
+	}
+
+	switch *format {
+	case "table":
+		writeTable(os.Stdout, rep)
+		if *hist {
+			writeHistograms(os.Stdout, rep)
+		}
+	case "json":
+		if err := writeJSON(os.Stdout, rep); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			status = 1
+		}
+	case "influx":
+		writeInflux(os.Stdout, rep)
+	}
+	if *summary != "" {
+		if err := appendSummary(*summary, rep, baseline); err != nil {
+			fmt.Fprintf(os.Stderr, "-summary: %v\n", err)
+			status = 1
+		}
+	}
+	if *push != "" {
+		if err := pushMetrics(*push, *pushJob, rep); err != nil {
+			fmt.Fprintf(os.Stderr, "-push: %v\n", err)
+			status = 1
+		}
+	}
+	return status
+}
+
+// One row of the results: a command and, for our own benchmarks, what
+// to build first.
+type runJob struct {
+	name      string
+	bench     benchmark // zero for external binaries
+	bin       string
+	args      []string
+	env       []string
+	input     string // file it reads, if any
+	inputHash string
+	stdin     bool // input goes on stdin rather than -file
+	expect    *expectation
+}
+
+func (j *runJob) useInput(dir, kind, size string, stdin bool) error {
+	path, hash, err := ensureInput(dir, kind, size)
+	if err != nil {
+		return fmt.Errorf("%s: %v", j.name, err)
+	}
+	j.input, j.inputHash, j.stdin = path, hash, stdin
+	if !stdin {
+		j.args = append(j.args, "-file", path)
+	}
+	return nil
+}
+
+// Rows for a suite file: each benchmark once per variant per size, named
+// like sort/merge/small, then the externals. filter applies to the names.
+func suiteJobs(c suiteConfig, all []benchmark, expected map[string]int64, outDir, inputDir, filter string) ([]runJob, error) {
+	only, err := regexp.Compile(filter)
+	if err != nil {
+		return nil, err
+	}
+	var jobs []runJob
+	for _, sb := range c.benchmarks {
+		i := slices.IndexFunc(all, func(b benchmark) bool { return b.name == sb.name })
+		if i < 0 {
+			return nil, fmt.Errorf("no benchmark %q", sb.name)
+		}
+		variants := sb.variants
+		if len(variants) == 0 {
+			variants = []string{""}
+		}
+		sizes := sb.sizes
+		if len(sizes) == 0 {
+			sizes = c.sizes
+		}
+		if len(sizes) == 0 {
+			sizes = []string{""}
+		}
+		for _, v := range variants {
+			for _, size := range sizes {
+				name := sb.name
+				args := strings.Fields(c.args)
+				if v != "" {
+					name += "/" + v
+					args = append(args, "-variant", v)
+				}
+				kind, hasInput := benchInputs[sb.name]
+				if size != "" {
+					name += "/" + size
+					if !hasInput {
+						args = append(args, "-size", size)
+					}
+				}
+				args = append(args, strings.Fields(sb.args)...)
+				if !only.MatchString(name) {
+					continue
+				}
+				j := runJob{name: name, bench: all[i], bin: filepath.Join(outDir, sb.name), args: args}
+				if hasInput {
+					if err := j.useInput(inputDir, kind, cmp.Or(size, "small"), false); err != nil {
+						return nil, err
+					}
+				}
+				jobs = append(jobs, j)
+			}
+		}
+	}
+	for _, e := range c.externals {
+		f := strings.Fields(e.command)
+		if !only.MatchString(e.name) {
+			continue
+		}
+		j := runJob{name: e.name, bin: f[0], args: f[1:], env: e.env}
+		if e.check != "" {
+			key := e.check + " " + e.workload
+			want, ok := expected[key]
+			if !ok {
+				return nil, fmt.Errorf("external %s: expected.go has nothing for %s", e.name, key)
+			}
+			j.expect = &expectation{key: key, want: want}
+			if e.result != "" {
+				j.expect.result = regexp.MustCompile(e.result) // checked in decodeSuite
+			}
+		}
+		if e.input != "" {
+			if err := j.useInput(inputDir, e.input, cmp.Or(e.size, "small"), true); err != nil {
+				return nil, err
+			}
+		}
+		jobs = append(jobs, j)
+	}
+	return jobs, nil
+}
+
+type runResult struct {
+	name   string
+	failed bool
+	kernel time.Duration // medians
+	wall   time.Duration
+	energy energy
+	// sha256 of the input file, if the benchmark reads one
+	inputHash string
+	// Largest -mem peaks over the runs
+	stackPeak, heapPeak int64
+	hasMem              bool
+	selfTimed           bool // kernel times came from MMLBENCH lines
+	checked             bool // output matched expected.go
+	// Every run's times, in run order
+	kernels []time.Duration
+	walls   []time.Duration
+}
+
+// Medians over runs of the job. Energy is read around the whole process,
+// so unlike the kernel time it includes startup and setup.
+func runMany(j runJob, runs int, zones []raplZone) (runResult, error) {
+	var kernels, walls []time.Duration
+	var pkgs, drams []float64
+	var hasDRAM, hasMem bool
+	var stackPeak, heapPeak int64
+	var selfTimed bool
+	for i := 0; i < runs; i++ {
+		var before []int64
+		if zones != nil {
+			var err error
+			if before, err = readRAPL(zones); err != nil {
+				return runResult{}, err
+			}
+		}
+		stdin := ""
+		if j.stdin {
+			stdin = j.input
+		}
+		// The first run's output is checked, the rest only timed
+		var out bytes.Buffer
+		var stdout io.Writer
+		if j.expect != nil && i == 0 {
+			stdout = &out
+		}
+		s, err := runOnceInput(j.bin, j.args, j.env, stdin, stdout)
+		if err != nil {
+			return runResult{}, err
+		}
+		if stdout != nil {
+			if err := j.expect.check(out.Bytes()); err != nil {
+				return runResult{}, fmt.Errorf("%s: %v", j.bin, err)
+			}
+		}
+		selfTimed = s.selfTimed
+		kernels = append(kernels, s.kernel)
+		walls = append(walls, s.wall)
+		stackPeak, heapPeak = max(stackPeak, s.stackPeak), max(heapPeak, s.heapPeak)
+		hasMem = hasMem || s.hasMem
+		if zones != nil {
+			after, err := readRAPL(zones)
+			if err != nil {
+				return runResult{}, err
+			}
+			e := energyBetween(zones, before, after)
+			pkgs = append(pkgs, e.pkg)
+			drams = append(drams, e.dram)
+			hasDRAM = e.hasDRAM
+		}
+	}
+	r := runResult{kernel: median(kernels), wall: median(walls), kernels: kernels, walls: walls,
+		stackPeak: stackPeak, heapPeak: heapPeak, hasMem: hasMem, selfTimed: selfTimed, checked: j.expect != nil}
+	if zones != nil {
+		r.energy = energy{pkg: median(pkgs), dram: median(drams), hasDRAM: hasDRAM}
+	}
+	return r, nil
+}
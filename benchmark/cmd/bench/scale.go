@@ -0,0 +1,132 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// The parallel variants, with the arguments that select them. Work is
+// fixed across thread counts (strong scaling); montecarlo-pi's -workers
+// follows GOMAXPROCS by default.
+var parallelVariants = []struct {
+	bench string
+	args  string
+}{
+	{"montecarlo-pi", "-variant parallel"},
+	{"pipeline", "-variant channels"},
+	{"pingpong", "-variant buffered -pairs 8"},
+}
+
+// bench scale: run each parallel variant under GOMAXPROCS set to every
+// count in -threads and print its scaling curve. Efficiency is speedup
+// over the first count divided by the thread ratio, so 100% is linear;
+// one number at one thread count can't tell scaling from luck. The
+// checksum has to be the same at every count, or the variant is
+// computing something different and the curve means nothing.
+func runScale(args []string) int {
+	fs := flag.NewFlagSet("scale", flag.ExitOnError)
+	dir := fs.String("dir", ".", "benchmark directory")
+	outDir := fs.String("o", "build/scale", "where builds go")
+	filter := fs.String("filter", "", "only benchmarks matching this regexp")
+	threadList := fs.String("threads", "1,2,4,8", "comma-separated GOMAXPROCS values to sweep")
+	runs := fs.Int("runs", 3, "runs per thread count; the median is reported")
+	benchArgs := fs.String("args", "-size small", "arguments passed before each variant's own")
+	fs.Parse(args)
+
+	if *runs < 1 {
+		fmt.Fprintln(os.Stderr, "-runs must be at least 1")
+		return 2
+	}
+	var threads []int
+	for _, s := range strings.Split(*threadList, ",") {
+		t, err := strconv.Atoi(strings.TrimSpace(s))
+		if err != nil || t < 1 {
+			fmt.Fprintf(os.Stderr, "-threads: bad count %q\n", s)
+			return 2
+		}
+		threads = append(threads, t)
+	}
+
+	all, shared, err := discover(*dir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	benches, err := selectBenchmarks(all, *filter, "")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	byName := map[string]benchmark{}
+	for _, b := range benches {
+		byName[b.name] = b
+	}
+	if err := os.MkdirAll(*outDir, 0o755); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	fmt.Println(describeHost())
+	status := 0
+	for _, pv := range parallelVariants {
+		b, ok := byName[pv.bench]
+		if !ok {
+			continue
+		}
+		fmt.Fprintf(os.Stderr, "%s...\n", b.name)
+		bin := filepath.Join(*outDir, b.name)
+		if err := build(b, shared, bin); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			status = 1
+			continue
+		}
+		runArgs := append(strings.Fields(*benchArgs), strings.Fields(pv.args)...)
+		times := make([]time.Duration, len(threads))
+		checksum := ""
+	sweep:
+		for i, t := range threads {
+			os.Setenv("GOMAXPROCS", strconv.Itoa(t))
+			kernels := make([]time.Duration, 0, *runs)
+			for r := 0; r < *runs; r++ {
+				var s sample
+				if s, err = runOnce(bin, runArgs, nil); err != nil {
+					break sweep
+				}
+				if i == 0 && r == 0 {
+					checksum = s.checksum
+				} else if s.checksum != checksum {
+					err = fmt.Errorf("%s: checksum %s at GOMAXPROCS=%d, but %s at %d",
+						b.name, s.checksum, t, checksum, threads[0])
+					break sweep
+				}
+				kernels = append(kernels, s.kernel)
+			}
+			times[i] = median(kernels)
+		}
+		os.Unsetenv("GOMAXPROCS")
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			status = 1
+			continue
+		}
+
+		fmt.Printf("\n%s %s\n", b.name, pv.args)
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "threads\tkernel\tspeedup\tefficiency")
+		for i, t := range threads {
+			speedup := float64(times[0]) / float64(times[i])
+			eff := speedup * float64(threads[0]) / float64(t)
+			fmt.Fprintf(tw, "%d\t%v\t%.2fx\t%3.0f%% %s\n", t, times[i].Round(time.Microsecond),
+				speedup, 100*eff, strings.Repeat("#", int(min(eff, 1.5)*20+0.5)))
+		}
+		tw.Flush()
+		fmt.Printf("checksum %s at every count\n", checksum)
+	}
+	return status
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// bench size: stripped executable size and startup latency next to the
+// kernel time. Startup is exec to the first byte on stdout of a -noop
+// run, so it covers loading and runtime init but none of the workload.
+// The first run after building is reported apart from the median, as
+// the closest we get to cold without dropping the page cache.
+func runSize(args []string) int {
+	fs := flag.NewFlagSet("size", flag.ExitOnError)
+	dir := fs.String("dir", ".", "benchmark directory")
+	outDir := fs.String("o", "build/size", "where builds go")
+	filter := fs.String("filter", "", "only benchmarks matching this regexp")
+	skip := fs.String("skip", "^lines$", "skip benchmarks matching this regexp (lines reads stdin)")
+	runs := fs.Int("runs", 3, "timed kernel runs; the median is reported")
+	startupRuns := fs.Int("startup-runs", 20, "-noop runs; the median is reported")
+	benchArgs := fs.String("args", "-size small", "arguments passed to every benchmark")
+	fs.Parse(args)
+
+	if *runs < 1 || *startupRuns < 1 {
+		fmt.Fprintln(os.Stderr, "-runs and -startup-runs must be at least 1")
+		return 2
+	}
+
+	all, shared, err := discover(*dir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	benches, err := selectBenchmarks(all, *filter, *skip)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	if err := os.MkdirAll(*outDir, 0o755); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	runArgs := strings.Fields(*benchArgs)
+
+	fmt.Println(describeHost())
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "benchmark\tstripped bytes\tstartup first\tstartup median\tkernel")
+	status := 0
+	for _, b := range benches {
+		fmt.Fprintf(os.Stderr, "%s...\n", b.name)
+		r, err := sizeOne(b, shared, *outDir, *runs, *startupRuns, runArgs)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			fmt.Fprintf(tw, "%s\tfailed\n", b.name)
+			status = 1
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%v\t%v\t%v\n", b.name, r.size,
+			r.first.Round(time.Microsecond), r.startup.Round(time.Microsecond), r.kernel.Round(time.Microsecond))
+	}
+	tw.Flush()
+	return status
+}
+
+type sizeResult struct {
+	size    int64
+	first   time.Duration
+	startup time.Duration
+	kernel  time.Duration
+}
+
+func sizeOne(b benchmark, shared []string, outDir string, runs, startupRuns int, runArgs []string) (sizeResult, error) {
+	var r sizeResult
+	bin := filepath.Join(outDir, b.name)
+	if err := build(b, shared, bin, "-ldflags=-s -w"); err != nil {
+		return r, err
+	}
+	info, err := os.Stat(bin)
+	if err != nil {
+		return r, err
+	}
+	r.size = info.Size()
+
+	samples := make([]time.Duration, 0, startupRuns)
+	for i := 0; i < startupRuns; i++ {
+		t, err := firstOutput(bin, []string{"-noop"})
+		if err != nil {
+			return r, err
+		}
+		samples = append(samples, t)
+	}
+	r.first = samples[0]
+	r.startup = median(samples)
+
+	r.kernel, err = measure(bin, runArgs, runs)
+	return r, err
+}
+
+// Time from starting bin to the first byte it writes to stdout.
+func firstOutput(bin string, args []string) (time.Duration, error) {
+	cmd := exec.Command(bin, args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return 0, err
+	}
+	start := time.Now()
+	if err := cmd.Start(); err != nil {
+		return 0, err
+	}
+	var first [1]byte
+	_, readErr := io.ReadFull(stdout, first[:])
+	elapsed := time.Since(start)
+	io.Copy(io.Discard, stdout)
+	if err := cmd.Wait(); err != nil {
+		return 0, fmt.Errorf("%s: %v\n%s", bin, err, stderr.String())
+	}
+	if readErr != nil {
+		return 0, fmt.Errorf("%s: no output: %v", bin, readErr)
+	}
+	return elapsed, nil
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"time"
+)
+
+// Two-sided Mann-Whitney U test: how likely two sets of timings are to
+// come from the same distribution. It only looks at ranks, so it doesn't
+// assume timings are normal (they're not: long right tails) and one
+// outlier can't swing it. Same test benchstat uses.
+func mannWhitney(a, b []float64) (p float64) {
+	m, n := len(a), len(b)
+	if m == 0 || n == 0 {
+		return 1
+	}
+	type obs struct {
+		v     float64
+		fromA bool
+	}
+	all := make([]obs, 0, m+n)
+	for _, v := range a {
+		all = append(all, obs{v, true})
+	}
+	for _, v := range b {
+		all = append(all, obs{v, false})
+	}
+	slices.SortFunc(all, func(x, y obs) int {
+		switch {
+		case x.v < y.v:
+			return -1
+		case x.v > y.v:
+			return 1
+		}
+		return 0
+	})
+
+	// Ranks from 1, ties get the average of the ranks they span
+	var rankA, tieTerm float64
+	ties := false
+	for i := 0; i < len(all); {
+		j := i
+		for j < len(all) && all[j].v == all[i].v {
+			j++
+		}
+		rank := float64(i+j+1) / 2
+		for k := i; k < j; k++ {
+			if all[k].fromA {
+				rankA += rank
+			}
+		}
+		if t := float64(j - i); t > 1 {
+			ties = true
+			tieTerm += t*t*t - t
+		}
+		i = j
+	}
+	u := rankA - float64(m*(m+1))/2
+
+	if !ties && m <= 20 && n <= 20 {
+		return exactMannWhitney(u, m, n)
+	}
+
+	// Normal approximation with tie and continuity corrections
+	N := float64(m + n)
+	mu := float64(m*n) / 2
+	sigma := math.Sqrt(float64(m*n) / 12 * ((N + 1) - tieTerm/(N*(N-1))))
+	if sigma == 0 {
+		return 1
+	}
+	z := (math.Abs(u-mu) - 0.5) / sigma
+	if z < 0 {
+		return 1
+	}
+	return math.Min(1, math.Erfc(z/math.Sqrt2))
+}
+
+// Exact p-value for small samples without ties, where the normal
+// approximation is poor. counts[u] is the number of orderings of m a's
+// and n b's giving that U, built up one sample size at a time.
+func exactMannWhitney(u float64, m, n int) float64 {
+	// f[i][j][u]: orderings of i a's and j b's with statistic u
+	f := make([][][]float64, m+1)
+	for i := range f {
+		f[i] = make([][]float64, n+1)
+		for j := range f[i] {
+			f[i][j] = make([]float64, i*j+1)
+			if i == 0 || j == 0 {
+				f[i][j][0] = 1
+				continue
+			}
+			for v := 0; v <= i*j; v++ {
+				// Largest value is an a (beats all j b's) or a b
+				if v >= j {
+					f[i][j][v] += f[i-1][j][v-j]
+				}
+				if v <= i*(j-1) {
+					f[i][j][v] += f[i][j-1][v]
+				}
+			}
+		}
+	}
+	counts := f[m][n]
+	var total, below, above float64
+	for v, c := range counts {
+		total += c
+		if float64(v) <= u {
+			below += c
+		}
+		if float64(v) >= u {
+			above += c
+		}
+	}
+	return math.Min(1, 2*math.Min(below, above)/total)
+}
+
+// Nearest-rank percentile, p in (0, 100]: the smallest sample with at
+// least p% of the samples at or below it. No interpolation, so it's
+// always a time that was actually measured.
+func percentile(xs []time.Duration, p float64) time.Duration {
+	sorted := slices.Clone(xs)
+	slices.Sort(sorted)
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	return sorted[max(rank, 1)-1]
+}
+
+func durationsToFloats(ds []time.Duration) []float64 {
+	fs := make([]float64, len(ds))
+	for i, d := range ds {
+		fs[i] = float64(d)
+	}
+	return fs
+}
+
+// The change from base to v, benchstat style: the median delta and p if
+// the difference is significant at alpha, "~" if it could be noise.
+func significance(v, base []time.Duration, alpha float64) string {
+	p := mannWhitney(durationsToFloats(v), durationsToFloats(base))
+	if p >= alpha {
+		return fmt.Sprintf("~ (p=%.3f)", p)
+	}
+	return fmt.Sprintf("%s (p=%.3f)", delta(median(v), median(base)), p)
+}
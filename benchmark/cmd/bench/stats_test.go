@@ -0,0 +1,151 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// Reference p-values are R's wilcox.test (exact for small samples
+// without ties, else exact=FALSE, correct=TRUE).
+func TestMannWhitney(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		a, b []float64
+		want float64
+		tol  float64 // 0: exact
+	}{
+		{"empty", nil, nil, 1, 0},
+		{"empty a", nil, []float64{1, 2, 3}, 1, 0},
+		{"empty b", []float64{1, 2, 3}, nil, 1, 0},
+		{"one each", []float64{1}, []float64{2}, 1, 0},
+		{"one and two", []float64{1}, []float64{2, 3}, 2.0 / 3, 0},
+		{"separated 3", []float64{1, 2, 3}, []float64{4, 5, 6}, 0.1, 1e-12},
+		{"separated 4", []float64{1, 2, 3, 4}, []float64{5, 6, 7, 8}, 2.0 / 70, 1e-12},
+		{"separated 5", []float64{1, 2, 3, 4, 5}, []float64{6, 7, 8, 9, 10}, 2.0 / 252, 1e-12},
+		{"interleaved 5", []float64{1, 3, 5, 7, 9}, []float64{2, 4, 6, 8, 10}, 174.0 / 252, 1e-12},
+		// All equal: no evidence either way.
+		{"identical", []float64{5, 5, 5}, []float64{5, 5, 5}, 1, 0},
+		// Ties switch to the normal approximation.
+		{"ties", []float64{1, 1, 2}, []float64{2, 3, 3}, 0.1102, 5e-4},
+		// So does more than 20 a side, ties or not.
+		{"separated 30", seq(0, 30), seq(30, 30), 0, 1e-9},
+		{"interleaved 30", seq2(0, 30), seq2(1, 30), 0.8303, 1e-4},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			for _, order := range []struct {
+				name string
+				a, b []float64
+			}{{"a,b", tc.a, tc.b}, {"b,a", tc.b, tc.a}} {
+				if got := mannWhitney(order.a, order.b); math.Abs(got-tc.want) > tc.tol {
+					t.Errorf("mannWhitney(%s) = %v, want %v ± %v", order.name, got, tc.want, tc.tol)
+				}
+			}
+		})
+	}
+}
+
+// start, start+1, ... n values.
+func seq(start float64, n int) []float64 {
+	xs := make([]float64, n)
+	for i := range xs {
+		xs[i] = start + float64(i)
+	}
+	return xs
+}
+
+// start, start+2, ... n values.
+func seq2(start float64, n int) []float64 {
+	xs := make([]float64, n)
+	for i := range xs {
+		xs[i] = start + 2*float64(i)
+	}
+	return xs
+}
+
+// The exact distribution is symmetric about mn/2, p is 1 at the centre
+// and 2/C(m+n, m) at either end.
+func TestExactMannWhitney(t *testing.T) {
+	for _, tc := range []struct{ m, n int }{{1, 1}, {1, 5}, {3, 3}, {4, 7}, {10, 10}, {20, 20}} {
+		mn := tc.m * tc.n
+		for u := 0; u <= mn; u++ {
+			p, q := exactMannWhitney(float64(u), tc.m, tc.n), exactMannWhitney(float64(mn-u), tc.m, tc.n)
+			if math.Abs(p-q) > 1e-12 {
+				t.Errorf("m=%d n=%d: p(%d) = %v, p(%d) = %v", tc.m, tc.n, u, p, mn-u, q)
+			}
+			if p <= 0 || p > 1 {
+				t.Errorf("m=%d n=%d u=%d: p = %v", tc.m, tc.n, u, p)
+			}
+		}
+		if p := exactMannWhitney(float64(mn)/2, tc.m, tc.n); p != 1 {
+			t.Errorf("m=%d n=%d: p at the centre = %v, want 1", tc.m, tc.n, p)
+		}
+		want := math.Min(1, 2/binomial(tc.m+tc.n, tc.m))
+		if p := exactMannWhitney(0, tc.m, tc.n); math.Abs(p-want) > 1e-12*want {
+			t.Errorf("m=%d n=%d: p(0) = %v, want %v", tc.m, tc.n, p, want)
+		}
+	}
+}
+
+func binomial(n, k int) float64 {
+	c := 1.0
+	for i := 1; i <= k; i++ {
+		c = c * float64(n-k+i) / float64(i)
+	}
+	return c
+}
+
+func TestPercentile(t *testing.T) {
+	tenToOne := []time.Duration{10, 9, 8, 7, 6, 5, 4, 3, 2, 1}
+	for _, tc := range []struct {
+		xs   []time.Duration
+		p    float64
+		want time.Duration
+	}{
+		{tenToOne, 50, 5},
+		{tenToOne, 90, 9},
+		{tenToOne, 95, 10},
+		{tenToOne, 100, 10},
+		{tenToOne, 10, 1},
+		{tenToOne, 0.1, 1},
+		{[]time.Duration{7}, 50, 7},
+		{[]time.Duration{7}, 100, 7},
+		{[]time.Duration{3, 1, 2}, 50, 2},
+		{[]time.Duration{2, 2, 1}, 50, 2},
+	} {
+		if got := percentile(tc.xs, tc.p); got != tc.want {
+			t.Errorf("percentile(%v, %v) = %v, want %v", tc.xs, tc.p, got, tc.want)
+		}
+	}
+	// Sorts a copy.
+	if tenToOne[0] != 10 {
+		t.Errorf("percentile sorted its argument: %v", tenToOne)
+	}
+}
+
+func TestSignificance(t *testing.T) {
+	ms := func(xs ...int) []time.Duration {
+		ds := make([]time.Duration, len(xs))
+		for i, x := range xs {
+			ds[i] = time.Duration(x) * time.Millisecond
+		}
+		return ds
+	}
+	for _, tc := range []struct {
+		name    string
+		v, base []time.Duration
+		want    string
+	}{
+		{"same", ms(10, 10, 10), ms(10, 10, 10), "~ (p=1.000)"},
+		{"noise", ms(10, 12, 14, 16, 18), ms(11, 13, 15, 17, 19), "~ (p=0.690)"},
+		// 3 a side can't get under 0.1.
+		{"too few", ms(20, 21, 22), ms(10, 11, 12), "~ (p=0.100)"},
+		{"slower", ms(20, 21, 22, 23, 24), ms(10, 11, 12, 13, 14), "+83.3% (p=0.008)"},
+		{"faster", ms(5, 6, 7, 8, 9), ms(10, 11, 12, 13, 14), "-41.7% (p=0.008)"},
+		{"no samples", nil, ms(10), "~ (p=1.000)"},
+	} {
+		if got := significance(tc.v, tc.base, 0.05); got != tc.want {
+			t.Errorf("%s: got %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
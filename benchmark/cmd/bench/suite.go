@@ -0,0 +1,212 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Finding, building and running the benchmarks. cmd/buildmatrix has its
+// own copy of the basics: the benchmarks build without a module, so the
+// two tools can't share a package.
+
+type benchmark struct {
+	name string
+	src  string
+}
+
+// Benchmarks are the .go files in dir that define main; the others are
+// shared sources linked into every benchmark, as the Makefile does,
+// except _test.go files.
+func discover(dir string) ([]benchmark, []string, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return nil, nil, err
+	}
+	var benches []benchmark
+	var shared []string
+	for _, f := range files {
+		// Tests build with go test against one benchmark, never into it
+		if strings.HasSuffix(f, "_test.go") {
+			continue
+		}
+		src, err := os.ReadFile(f)
+		if err != nil {
+			return nil, nil, err
+		}
+		if bytes.Contains(src, []byte("\nfunc main() {")) {
+			benches = append(benches, benchmark{strings.TrimSuffix(filepath.Base(f), ".go"), f})
+		} else {
+			shared = append(shared, f)
+		}
+	}
+	return benches, shared, nil
+}
+
+// Benchmarks whose names match only and don't match skip; an empty
+// pattern matches nothing for skip and everything for only.
+func selectBenchmarks(benches []benchmark, only, skip string) ([]benchmark, error) {
+	onlyRe, err := regexp.Compile(only)
+	if err != nil {
+		return nil, err
+	}
+	skipRe, err := regexp.Compile(skip)
+	if err != nil {
+		return nil, err
+	}
+	var out []benchmark
+	for _, b := range benches {
+		if onlyRe.MatchString(b.name) && (skip == "" || !skipRe.MatchString(b.name)) {
+			out = append(out, b)
+		}
+	}
+	return out, nil
+}
+
+// go build b with the shared sources into bin, with extra build flags.
+func build(b benchmark, shared []string, bin string, flags ...string) error {
+	args := append([]string{"build", "-o", bin}, flags...)
+	args = append(args, b.src)
+	args = append(args, shared...)
+	cmd := exec.Command("go", args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("go build %s: %v\n%s", b.name, err, stderr.String())
+	}
+	return nil
+}
+
+var (
+	mmlbenchNs       = regexp.MustCompile(`^MMLBENCH .*\bns=(\d+)\b`)
+	mmlbenchChecksum = regexp.MustCompile(`^MMLBENCH .*\bchecksum=(\S+)`)
+	mmlbenchMem      = regexp.MustCompile(`^MMLBENCH .*\bstack_peak=(\d+) heap_peak=(\d+)`)
+)
+
+type sample struct {
+	kernel    time.Duration
+	wall      time.Duration
+	selfTimed bool   // printed MMLBENCH lines, so kernel isn't just wall
+	checksum  string // of the MMLBENCH lines, space-separated
+	// Peak bytes from -mem, the largest over the MMLBENCH lines
+	stackPeak, heapPeak int64
+	hasMem              bool
+}
+
+// Runs bin once, with env added to the environment. The kernel time is
+// the sum of the ns fields of its MMLBENCH lines, or wall time if it
+// printed none.
+func runOnce(bin string, args, env []string) (sample, error) {
+	return runOnceInput(bin, args, env, "", nil)
+}
+
+// runOnce with the file stdin on standard input, if it isn't empty, and
+// standard output going to stdout, if it isn't nil.
+func runOnceInput(bin string, args, env []string, stdin string, stdout io.Writer) (sample, error) {
+	cmd := exec.Command(bin, args...)
+	cmd.Stdout = stdout
+	if env != nil {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	if stdin != "" {
+		f, err := os.Open(stdin)
+		if err != nil {
+			return sample{}, err
+		}
+		defer f.Close()
+		cmd.Stdin = f
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	start := time.Now()
+	if err := cmd.Run(); err != nil {
+		return sample{}, fmt.Errorf("%s: %v\n%s", bin, err, stderr.String())
+	}
+	s := sample{wall: time.Since(start)}
+
+	sc := bufio.NewScanner(&stderr)
+	for sc.Scan() {
+		if m := mmlbenchNs.FindStringSubmatch(sc.Text()); m != nil {
+			ns, _ := strconv.ParseInt(m[1], 10, 64)
+			s.kernel += time.Duration(ns)
+			s.selfTimed = true
+		}
+		if m := mmlbenchChecksum.FindStringSubmatch(sc.Text()); m != nil {
+			s.checksum = strings.TrimSpace(s.checksum + " " + m[1])
+		}
+		if m := mmlbenchMem.FindStringSubmatch(sc.Text()); m != nil {
+			stack, _ := strconv.ParseInt(m[1], 10, 64)
+			heap, _ := strconv.ParseInt(m[2], 10, 64)
+			s.stackPeak, s.heapPeak = max(s.stackPeak, stack), max(s.heapPeak, heap)
+			s.hasMem = true
+		}
+	}
+	if !s.selfTimed {
+		s.kernel = s.wall
+	}
+	return s, nil
+}
+
+func runKernel(bin string, args []string) (time.Duration, error) {
+	s, err := runOnce(bin, args, nil)
+	return s.kernel, err
+}
+
+// Kernel times of runs runs of bin.
+func measureSamples(bin string, args []string, runs int) ([]time.Duration, error) {
+	samples := make([]time.Duration, 0, runs)
+	for i := 0; i < runs; i++ {
+		t, err := runKernel(bin, args)
+		if err != nil {
+			return nil, err
+		}
+		samples = append(samples, t)
+	}
+	return samples, nil
+}
+
+// Median kernel time over runs.
+func measure(bin string, args []string, runs int) (time.Duration, error) {
+	samples, err := measureSamples(bin, args, runs)
+	if err != nil {
+		return 0, err
+	}
+	return median(samples), nil
+}
+
+func median[T time.Duration | float64](xs []T) T {
+	sorted := slices.Clone(xs)
+	slices.Sort(sorted)
+	return sorted[len(sorted)/2]
+}
+
+// One comment line naming the toolchain and machine, to head a report.
+func describeHost() string {
+	cpu := "unknown cpu"
+	if info, err := os.ReadFile("/proc/cpuinfo"); err == nil {
+		for _, line := range strings.Split(string(info), "\n") {
+			if name, ok := strings.CutPrefix(line, "model name"); ok {
+				cpu = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(name), ":"))
+				break
+			}
+		}
+	}
+	return fmt.Sprintf("# %s %s/%s, %s, %d CPUs", runtime.Version(), runtime.GOOS, runtime.GOARCH, cpu, runtime.NumCPU())
+}
+
+func delta(v, base time.Duration) string {
+	if base == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%+.1f%%", 100*(float64(v)-float64(base))/float64(base))
+}
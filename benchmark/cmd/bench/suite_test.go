@@ -0,0 +1,117 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestDiscover(t *testing.T) {
+	dir := t.TempDir()
+	for name, src := range map[string]string{
+		"sieve.go":         "package main\n\nfunc main() {\n}\n",
+		"sort.go":          "package main\n\nimport \"os\"\n\nfunc main() {\n\tos.Exit(0)\n}\n",
+		"rng.go":           "package main\n\ntype lcg struct{}\n",
+		"check.go":         "package main\n\n// not func main() {\n",
+		"sieve_test.go":    "package main\n\nfunc main() {\n}\n",
+		"notes.txt":        "func main() {\n",
+		"sieve_kernels.go": "package main\n\nvar kernels = 1\n",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	benches, shared, err := discover(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []benchmark{{"sieve", filepath.Join(dir, "sieve.go")}, {"sort", filepath.Join(dir, "sort.go")}}
+	if !slices.Equal(benches, want) {
+		t.Errorf("benchmarks %v, want %v", benches, want)
+	}
+	wantShared := []string{filepath.Join(dir, "check.go"), filepath.Join(dir, "rng.go"), filepath.Join(dir, "sieve_kernels.go")}
+	if !slices.Equal(shared, wantShared) {
+		t.Errorf("shared %v, want %v", shared, wantShared)
+	}
+}
+
+func TestSelectBenchmarks(t *testing.T) {
+	var all []benchmark
+	for _, name := range []string{"sieve", "sieve-opt", "sort", "matmul", "matmul-opt"} {
+		all = append(all, benchmark{name: name})
+	}
+	for _, tc := range []struct {
+		only, skip string
+		want       []string
+	}{
+		{"", "", []string{"sieve", "sieve-opt", "sort", "matmul", "matmul-opt"}},
+		{"sieve", "", []string{"sieve", "sieve-opt"}},
+		{"^sieve$", "", []string{"sieve"}},
+		{"", "opt", []string{"sieve", "sort", "matmul"}},
+		{"sieve|matmul", "-opt$", []string{"sieve", "matmul"}},
+		{"nothing", "", nil},
+		{"", ".", nil},
+	} {
+		got, err := selectBenchmarks(all, tc.only, tc.skip)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, b := range got {
+			names = append(names, b.name)
+		}
+		if !slices.Equal(names, tc.want) {
+			t.Errorf("only %q skip %q: got %v, want %v", tc.only, tc.skip, names, tc.want)
+		}
+	}
+	for _, bad := range [][2]string{{"(", ""}, {"", "["}} {
+		if _, err := selectBenchmarks(all, bad[0], bad[1]); err == nil {
+			t.Errorf("only %q skip %q: want an error", bad[0], bad[1])
+		}
+	}
+}
+
+// The upper median for even counts, so it's always a measured time.
+func TestMedian(t *testing.T) {
+	for _, tc := range []struct {
+		xs   []time.Duration
+		want time.Duration
+	}{
+		{[]time.Duration{5}, 5},
+		{[]time.Duration{3, 1, 2}, 2},
+		{[]time.Duration{4, 1, 3, 2}, 3},
+		{[]time.Duration{7, 7, 1}, 7},
+	} {
+		if got := median(tc.xs); got != tc.want {
+			t.Errorf("median(%v) = %v, want %v", tc.xs, got, tc.want)
+		}
+	}
+	if got := median([]float64{0.5, 0.25, 1}); got != 0.5 {
+		t.Errorf("float median = %v, want 0.5", got)
+	}
+	xs := []time.Duration{3, 1, 2}
+	median(xs)
+	if !slices.Equal(xs, []time.Duration{3, 1, 2}) {
+		t.Errorf("median sorted its argument: %v", xs)
+	}
+}
+
+func TestDelta(t *testing.T) {
+	for _, tc := range []struct {
+		v, base time.Duration
+		want    string
+	}{
+		{110, 100, "+10.0%"},
+		{90, 100, "-10.0%"},
+		{100, 100, "+0.0%"},
+		{3, 2, "+50.0%"},
+		{1, 3, "-66.7%"},
+		{5, 0, "-"},
+	} {
+		if got := delta(tc.v, tc.base); got != tc.want {
+			t.Errorf("delta(%d, %d) = %q, want %q", tc.v, tc.base, got, tc.want)
+		}
+	}
+}
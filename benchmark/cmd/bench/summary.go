@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Markdown summary for CI job pages. GitHub renders whatever a step
+// appends to $GITHUB_STEP_SUMMARY; other CI systems can take the file
+// from -summary as an artifact. With a baseline (a previous run's
+// -format=influx output) it leads with the biggest regressions.
+
+// Kernel slowdowns below this aren't listed as regressions: on shared CI
+// runners a few percent either way is weather.
+const regressionThreshold = 0.05
+
+const maxRegressions = 5
+
+// Kernel times from InfluxDB line protocol written by writeInflux.
+func readBaseline(path string) (map[string]time.Duration, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	base := map[string]time.Duration{}
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := sc.Text()
+		if !strings.HasPrefix(line, "mmlbench,") {
+			continue
+		}
+		parts := splitUnescaped(line, ' ')
+		if len(parts) < 2 {
+			continue
+		}
+		var name string
+		for _, tag := range splitUnescaped(parts[0], ',')[1:] {
+			if v, ok := strings.CutPrefix(tag, "benchmark="); ok {
+				name = unescapeInflux(v)
+			}
+		}
+		for _, field := range splitUnescaped(parts[1], ',') {
+			if v, ok := strings.CutPrefix(field, "kernel_ns="); ok {
+				ns, err := strconv.ParseInt(strings.TrimSuffix(v, "i"), 10, 64)
+				if err != nil {
+					return nil, fmt.Errorf("%s: %q: %v", path, line, err)
+				}
+				base[name] = time.Duration(ns)
+			}
+		}
+	}
+	return base, sc.Err()
+}
+
+// Splits s at sep, except where sep is escaped with a backslash.
+func splitUnescaped(s string, sep byte) []string {
+	var parts []string
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case sep:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// Undoes influxTag.
+var unescapeInflux = strings.NewReplacer(`\\`, `\`, `\,`, ",", `\ `, " ", `\=`, "=").Replace
+
+func writeSummary(w io.Writer, rep runReport, baseline map[string]time.Duration) {
+	fmt.Fprintln(w, "### Benchmarks")
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "%s, `%s`. Noise: %v.\n\n", strings.TrimPrefix(rep.host, "# "), rep.args, rep.noise)
+	for _, f := range rep.inputFiles() {
+		fmt.Fprintf(w, "Input `%s`: sha256 `%s`.\n\n", f, rep.inputs[f])
+	}
+
+	type change struct {
+		name      string
+		base, now time.Duration
+		d         float64
+	}
+	var regressions []change
+	failed := 0
+	for _, r := range rep.results {
+		if r.failed {
+			failed++
+			continue
+		}
+		if b, ok := baseline[r.name]; ok && b > 0 {
+			if d := float64(r.kernel-b) / float64(b); d > regressionThreshold {
+				regressions = append(regressions, change{r.name, b, r.kernel, d})
+			}
+		}
+	}
+	if failed > 0 {
+		fmt.Fprintf(w, "**%d failed.**\n\n", failed)
+	}
+	if baseline != nil {
+		if len(regressions) == 0 {
+			fmt.Fprintf(w, "No kernel regressions over %.0f%%.\n\n", 100*regressionThreshold)
+		} else {
+			slices.SortFunc(regressions, func(a, b change) int {
+				switch {
+				case a.d > b.d:
+					return -1
+				case a.d < b.d:
+					return 1
+				}
+				return strings.Compare(a.name, b.name)
+			})
+			if len(regressions) > maxRegressions {
+				regressions = regressions[:maxRegressions]
+			}
+			fmt.Fprintln(w, "**Top regressions** (kernel time)")
+			fmt.Fprintln(w)
+			fmt.Fprintln(w, "| benchmark | baseline | now | delta |")
+			fmt.Fprintln(w, "|---|--:|--:|--:|")
+			for _, c := range regressions {
+				fmt.Fprintf(w, "| %s | %v | %v | %+.1f%% |\n", c.name,
+					c.base.Round(time.Microsecond), c.now.Round(time.Microsecond), 100*c.d)
+			}
+			fmt.Fprintln(w)
+		}
+	}
+
+	fmt.Fprintln(w, "<details><summary>All benchmarks</summary>")
+	fmt.Fprintln(w)
+	fmt.Fprint(w, "| benchmark | kernel | wall |")
+	if baseline != nil {
+		fmt.Fprint(w, " baseline | delta |")
+	}
+	fmt.Fprintln(w)
+	fmt.Fprint(w, "|---|--:|--:|")
+	if baseline != nil {
+		fmt.Fprint(w, "--:|--:|")
+	}
+	fmt.Fprintln(w)
+	for _, r := range rep.results {
+		if r.failed {
+			fmt.Fprintf(w, "| %s | failed | |", r.name)
+			if baseline != nil {
+				fmt.Fprint(w, " | |")
+			}
+			fmt.Fprintln(w)
+			continue
+		}
+		fmt.Fprintf(w, "| %s | %v | %v |", r.name, r.kernel.Round(time.Microsecond), r.wall.Round(time.Microsecond))
+		if baseline != nil {
+			if b, ok := baseline[r.name]; ok {
+				fmt.Fprintf(w, " %v | %s |", b.Round(time.Microsecond), delta(r.kernel, b))
+			} else {
+				fmt.Fprint(w, " - | new |")
+			}
+		}
+		fmt.Fprintln(w)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "</details>")
+	fmt.Fprintln(w)
+}
+
+// Appends, as GitHub expects: other steps may have written to the same
+// file already.
+func appendSummary(path string, rep runReport, baseline map[string]time.Duration) error {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	writeSummary(f, rep, baseline)
+	return f.Close()
+}
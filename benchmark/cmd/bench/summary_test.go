@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bytes"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeBaseline(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "baseline.influx")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// What writeInflux writes, readBaseline reads back, names and all.
+func TestReadBaselineRoundTrip(t *testing.T) {
+	rep := testReport()
+	rep.results = append(rep.results, runResult{name: `odd\ name,with=everything\`, kernel: time.Second, kernels: []time.Duration{time.Second}})
+	var b bytes.Buffer
+	writeInflux(&b, rep)
+
+	got, err := readBaseline(writeBaseline(t, b.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]time.Duration{
+		"sieve":                      7 * time.Millisecond,
+		"lines mml,a=b":              3 * time.Millisecond,
+		`odd\ name,with=everything\`: time.Second,
+	}
+	if !maps.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestReadBaseline(t *testing.T) {
+	for _, tc := range []struct {
+		name, in string
+		want     map[string]time.Duration
+		err      string // substring of the error, "" for none
+	}{
+		{name: "empty", in: "", want: map[string]time.Duration{}},
+		// Only mmlbench points count; noise, other measurements,
+		// comments and lines without fields are skipped.
+		{name: "other lines", in: `# written by bench run
+mmlbench_noise,host=a cv=0.01,calibration_ns=2000000i 1
+cpu,host=a usage=3 1
+mmlbench,benchmark=sieve,host=a kernel_ns=5i 1
+
+mmlbench,benchmark=broken
+mmlbench,benchmark=sort,host=a kernel_p90_ns=9i,kernel_ns=7i,wall_ns=8i 1
+`, want: map[string]time.Duration{"sieve": 5, "sort": 7}},
+		// A later point for the same benchmark wins.
+		{name: "repeated", in: "mmlbench,benchmark=sieve kernel_ns=5i 1\nmmlbench,benchmark=sieve kernel_ns=6i 2\n",
+			want: map[string]time.Duration{"sieve": 6}},
+		{name: "no timestamp", in: "mmlbench,benchmark=sieve kernel_ns=5i\n", want: map[string]time.Duration{"sieve": 5}},
+		{name: "no i suffix", in: "mmlbench,benchmark=sieve kernel_ns=5 1\n", want: map[string]time.Duration{"sieve": 5}},
+		{name: "bad kernel_ns", in: "mmlbench,benchmark=sieve kernel_ns=5.5 1\n", err: `"mmlbench,benchmark=sieve kernel_ns=5.5 1"`},
+		{name: "empty kernel_ns", in: "mmlbench,benchmark=sieve kernel_ns= 1\n", err: "invalid syntax"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			path := writeBaseline(t, tc.in)
+			got, err := readBaseline(path)
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) || !strings.Contains(err.Error(), path) {
+					t.Fatalf("got %v, %v; want error naming the file and containing %q", got, err, tc.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !maps.Equal(got, tc.want) {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+
+	if _, err := readBaseline(filepath.Join(t.TempDir(), "missing")); !os.IsNotExist(err) {
+		t.Errorf("missing file: got %v, want not exist", err)
+	}
+}
+
+func TestSplitUnescaped(t *testing.T) {
+	for _, tc := range []struct {
+		in   string
+		sep  byte
+		want []string
+	}{
+		{"", ',', []string{""}},
+		{"a", ',', []string{"a"}},
+		{"a,b,,c", ',', []string{"a", "b", "", "c"}},
+		{",", ',', []string{"", ""}},
+		{`a\,b,c`, ',', []string{`a\,b`, "c"}},
+		{`a\\,b`, ',', []string{`a\\`, "b"}},
+		{`a\ b c`, ' ', []string{`a\ b`, "c"}},
+		// A trailing backslash escapes nothing.
+		{`a,b\`, ',', []string{"a", `b\`}},
+	} {
+		if got := splitUnescaped(tc.in, tc.sep); !slices.Equal(got, tc.want) {
+			t.Errorf("splitUnescaped(%q, %q) = %q, want %q", tc.in, tc.sep, got, tc.want)
+		}
+	}
+}
+
+func TestUnescapeInflux(t *testing.T) {
+	for _, s := range []string{"", "sieve", "a b", "a,b=c", `C:\bin`, `trailing \`, `\,`, `\\ `} {
+		if got := unescapeInflux(influxTag.Replace(s)); got != s {
+			t.Errorf("%q: round trip gave %q", s, got)
+		}
+	}
+}
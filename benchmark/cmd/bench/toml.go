@@ -0,0 +1,193 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Just enough TOML for suite files, since we build without a module and
+// can't pull in a library: comments, key = value with strings, integers,
+// booleans and (possibly multi-line) arrays of those, [table] and
+// [[array of tables]] headers. No inline tables, dotted keys, literal
+// strings or dates.
+
+type tomlTable map[string]any
+
+func parseTOML(src string) (tomlTable, error) {
+	root := tomlTable{}
+	cur := root
+	lines := strings.Split(src, "\n")
+	for i := 0; i < len(lines); i++ {
+		lineNo := i + 1
+		line := strings.TrimSpace(stripComment(lines[i]))
+		if line == "" {
+			continue
+		}
+
+		if name, ok := strings.CutPrefix(line, "[["); ok {
+			name, ok = strings.CutSuffix(name, "]]")
+			if !ok {
+				return nil, fmt.Errorf("line %d: bad table header %q", lineNo, line)
+			}
+			name = strings.TrimSpace(name)
+			list, _ := root[name].([]tomlTable)
+			if _, exists := root[name]; exists && list == nil {
+				return nil, fmt.Errorf("line %d: %s is not an array of tables", lineNo, name)
+			}
+			cur = tomlTable{}
+			root[name] = append(list, cur)
+			continue
+		}
+		if name, ok := strings.CutPrefix(line, "["); ok {
+			name, ok = strings.CutSuffix(name, "]")
+			if !ok {
+				return nil, fmt.Errorf("line %d: bad table header %q", lineNo, line)
+			}
+			name = strings.TrimSpace(name)
+			if _, exists := root[name]; exists {
+				return nil, fmt.Errorf("line %d: table %s defined twice", lineNo, name)
+			}
+			cur = tomlTable{}
+			root[name] = cur
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: want key = value, got %q", lineNo, line)
+		}
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		if key == "" {
+			return nil, fmt.Errorf("line %d: empty key", lineNo)
+		}
+		// Arrays can run over several lines
+		for strings.HasPrefix(value, "[") && !arrayClosed(value) && i+1 < len(lines) {
+			i++
+			value += " " + strings.TrimSpace(stripComment(lines[i]))
+		}
+		v, rest, err := parseTOMLValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s: %v", lineNo, key, err)
+		}
+		if rest = strings.TrimSpace(rest); rest != "" {
+			return nil, fmt.Errorf("line %d: %s: trailing %q", lineNo, key, rest)
+		}
+		if _, exists := cur[key]; exists {
+			return nil, fmt.Errorf("line %d: %s set twice", lineNo, key)
+		}
+		cur[key] = v
+	}
+	return root, nil
+}
+
+// Drops a # comment, minding # inside strings.
+func stripComment(line string) string {
+	inString := false
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			if inString {
+				i++
+			}
+		case '"':
+			inString = !inString
+		case '#':
+			if !inString {
+				return line[:i]
+			}
+		}
+	}
+	return line
+}
+
+func arrayClosed(s string) bool {
+	depth, inString := 0, false
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '\\' && inString:
+			i++
+		case c == '"':
+			inString = !inString
+		case c == '[' && !inString:
+			depth++
+		case c == ']' && !inString:
+			depth--
+		}
+	}
+	return depth == 0
+}
+
+// Parses one value off the front of s and returns the rest.
+func parseTOMLValue(s string) (any, string, error) {
+	s = strings.TrimLeft(s, " \t")
+	switch {
+	case s == "":
+		return nil, "", fmt.Errorf("missing value")
+	case s[0] == '"':
+		var b strings.Builder
+		for i := 1; i < len(s); i++ {
+			switch s[i] {
+			case '"':
+				return b.String(), s[i+1:], nil
+			case '\\':
+				if i+1 == len(s) {
+					return nil, "", fmt.Errorf("unterminated string")
+				}
+				i++
+				switch s[i] {
+				case '"', '\\':
+					b.WriteByte(s[i])
+				case 'n':
+					b.WriteByte('\n')
+				case 't':
+					b.WriteByte('\t')
+				default:
+					return nil, "", fmt.Errorf(`unsupported escape \%c`, s[i])
+				}
+			default:
+				b.WriteByte(s[i])
+			}
+		}
+		return nil, "", fmt.Errorf("unterminated string")
+	case s[0] == '[':
+		var list []any
+		s = strings.TrimLeft(s[1:], " \t")
+		for {
+			if s == "" {
+				return nil, "", fmt.Errorf("unclosed array")
+			}
+			if strings.HasPrefix(s, "]") {
+				return list, s[1:], nil
+			}
+			v, rest, err := parseTOMLValue(s)
+			if err != nil {
+				return nil, "", err
+			}
+			list = append(list, v)
+			s = strings.TrimLeft(rest, " \t")
+			if strings.HasPrefix(s, ",") {
+				s = strings.TrimLeft(s[1:], " \t")
+			} else if s != "" && !strings.HasPrefix(s, "]") {
+				return nil, "", fmt.Errorf("want , or ] in array, got %q", s)
+			}
+		}
+	default:
+		end := strings.IndexAny(s, ",] \t")
+		if end < 0 {
+			end = len(s)
+		}
+		word, rest := s[:end], s[end:]
+		switch word {
+		case "true":
+			return true, rest, nil
+		case "false":
+			return false, rest, nil
+		}
+		n, err := strconv.ParseInt(strings.ReplaceAll(word, "_", ""), 10, 64)
+		if err != nil {
+			return nil, "", fmt.Errorf("can't parse %q", word)
+		}
+		return n, rest, nil
+	}
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseTOML(t *testing.T) {
+	for _, tc := range []struct {
+		name, in string
+		want     tomlTable
+	}{
+		{"empty", "", tomlTable{}},
+		{"comments only", "# a suite\n\n   # indented\n", tomlTable{}},
+		{"scalars", `runs = 10
+big = 1_000_000
+neg = -3
+on = true
+off = false
+name = "sieve"
+`, tomlTable{"runs": int64(10), "big": int64(1_000_000), "neg": int64(-3), "on": true, "off": false, "name": "sieve"}},
+		{"no spaces", "a=1\nb=\"x\"", tomlTable{"a": int64(1), "b": "x"}},
+		{"trailing comments", `runs = 10 # per variant
+name = "sieve" # the Go one
+`, tomlTable{"runs": int64(10), "name": "sieve"}},
+		// # and = inside strings are not comments or separators.
+		{"quoted", `a = "#1 = first"
+b = "say \"hi\" # not a comment" # a comment
+c = "tab\there\nnewline \\ backslash"
+d = ""
+`, tomlTable{"a": "#1 = first", "b": `say "hi" # not a comment`, "c": "tab\there\nnewline \\ backslash", "d": ""}},
+		{"arrays", `sizes = ["small", "medium"]
+empty = []
+nums = [1, 2, 3,]
+nested = [[1, 2], ["a]"]]
+`, tomlTable{
+			"sizes":  []any{"small", "medium"},
+			"empty":  []any(nil),
+			"nums":   []any{int64(1), int64(2), int64(3)},
+			"nested": []any{[]any{int64(1), int64(2)}, []any{"a]"}},
+		}},
+		{"multi-line array", `env = [
+  "A=1", # first
+  "B=]#", # a ] and a # in a string
+  "C=3",
+]
+after = 1
+`, tomlTable{"env": []any{"A=1", "B=]#", "C=3"}, "after": int64(1)}},
+		{"tables", `top = 1
+[meta]
+owner = "me"
+[[benchmark]]
+name = "sieve"
+[[benchmark]] # another
+name = "sort"
+variants = ["quick"]
+[ other ]
+x = 2
+`, tomlTable{
+			"top":  int64(1),
+			"meta": tomlTable{"owner": "me"},
+			"benchmark": []tomlTable{
+				{"name": "sieve"},
+				{"name": "sort", "variants": []any{"quick"}},
+			},
+			"other": tomlTable{"x": int64(2)},
+		}},
+		// Keys set before any header stay top level; the same key may
+		// appear once in each table.
+		{"same key in each table", `name = "top"
+[[benchmark]]
+name = "a"
+[[benchmark]]
+name = "b"
+`, tomlTable{"name": "top", "benchmark": []tomlTable{{"name": "a"}, {"name": "b"}}}},
+		{"crlf", "a = 1\r\nb = \"x\"\r\n", tomlTable{"a": int64(1), "b": "x"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := parseTOML(tc.in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got  %#v\nwant %#v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseTOMLErrors(t *testing.T) {
+	for _, tc := range []struct{ name, in, err string }{
+		{"no equals", "runs 10", `line 1: want key = value, got "runs 10"`},
+		{"empty key", " = 1", "line 1: empty key"},
+		{"missing value", "runs =", "line 1: runs: missing value"},
+		{"missing value after comment", "a = 1\nruns = # none", "line 2: runs: missing value"},
+		{"bad int", "runs = ten", `line 1: runs: can't parse "ten"`},
+		{"float", "runs = 1.5", `can't parse "1.5"`},
+		{"bare underscore", "runs = _", `can't parse "_"`},
+		{"unterminated string", `name = "sieve`, "line 1: name: unterminated string"},
+		{"escaped last quote", `name = "sieve\"`, "unterminated string"},
+		{"unsupported escape", `name = "a\qb"`, `unsupported escape \q`},
+		{"unicode escape", `name = "\u00e9"`, `unsupported escape \u`},
+		{"literal string", "name = 'sieve'", `can't parse "'sieve'"`},
+		{"trailing", `name = "a" "b"`, `line 1: name: trailing "\"b\""`},
+		{"trailing after array", "a = [1] 2", `trailing "2"`},
+		{"array separator", "a = [1 2]", "want , or ] in array"},
+		{"unclosed array", "a = [1,\n2,\n", "line 1: a: unclosed array"},
+		{"unclosed array at value", "a = [1", "line 1: a: unclosed array"},
+		{"key twice", "a = 1\nb = 2\na = 3", "line 3: a set twice"},
+		{"key twice in table", "[[benchmark]]\nname = \"a\"\nname = \"b\"", "line 3: name set twice"},
+		{"table twice", "[meta]\n[meta]", "line 2: table meta defined twice"},
+		{"table over key", "meta = 1\n[meta]", "line 2: table meta defined twice"},
+		{"table then array", "[benchmark]\n[[benchmark]]", "line 2: benchmark is not an array of tables"},
+		{"key then array", "benchmark = 1\n[[benchmark]]", "benchmark is not an array of tables"},
+		{"array then table", "[[benchmark]]\n[benchmark]", "table benchmark defined twice"},
+		{"bad header", "[meta", `line 1: bad table header "[meta"`},
+		{"bad array header", "[[benchmark]", `bad table header "[[benchmark]"`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := parseTOML(tc.in)
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Fatalf("got %v, %v; want error containing %q", got, err, tc.err)
+			}
+		})
+	}
+}
+
+func TestStripComment(t *testing.T) {
+	for in, want := range map[string]string{
+		"":                      "",
+		"# all comment":         "",
+		"a = 1 # c":             "a = 1 ",
+		`a = "#"`:               `a = "#"`,
+		`a = "#" # c`:           `a = "#" `,
+		`a = "\"#" # c`:         `a = "\"#" `,
+		`a = "\\" # c`:          `a = "\\" `,
+		`a = ["x", "#y"] # "z"`: `a = ["x", "#y"] `,
+	} {
+		if got := stripComment(in); got != want {
+			t.Errorf("stripComment(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestArrayClosed(t *testing.T) {
+	for in, want := range map[string]bool{
+		"[]":          true,
+		"[":           false,
+		"[1, 2":       false,
+		"[[1], [2]]":  true,
+		"[[1], [2]":   false,
+		`["]"`:        false,
+		`["]"]`:       true,
+		`["\"]"`:      false,
+		`["\\"]`:      true,
+		`["a[", "b"]`: true,
+	} {
+		if got := arrayClosed(in); got != want {
+			t.Errorf("arrayClosed(%q) = %v, want %v", in, got, want)
+		}
+	}
+}
+
+// The TOML files in the tree parse.
+func TestParseTOMLFiles(t *testing.T) {
+	for _, path := range []string{"../../suite.toml", "../../benchmarks.toml"} {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := parseTOML(string(src)); err != nil {
+			t.Errorf("%s: %v", path, err)
+		}
+	}
+}
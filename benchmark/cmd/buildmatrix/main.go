@@ -0,0 +1,373 @@
+// Buildmatrix builds every Go benchmark under several compiler flag sets,
+// runs each build and tabulates kernel time against the default build.
+// It answers "what do bounds checks and inlining cost here" without
+// rebuilding by hand.
+//
+// -mode goamd64 sweeps GOAMD64=v1..v4 instead and adds binary sizes; the
+// MML side is LLVM output built for the native CPU, so the Go side's
+// target level belongs in the results. -arm64 adds a GOARCH=arm64 cross
+// build to that table, for size only.
+//
+// Run it from the benchmark directory:
+//
+//	go run cmd/buildmatrix/main.go
+//	go run cmd/buildmatrix/main.go -filter 'sieve|matmul' -runs 5 -args '-size medium'
+//	go run cmd/buildmatrix/main.go -mode goamd64 -arm64
+//
+// Times come from the MMLBENCH lines the benchmarks print (kernel only,
+// summed when a run prints several); the median of -runs is reported.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// A build configuration: gcflags for the benchmark's own package plus
+// extra environment for go build.
+type config struct {
+	name    string
+	gcflags string
+	env     []string
+	// The build's compiler output is the result; its binary is the
+	// default one, so it is not run again.
+	reportOnly bool
+	// Cross builds can't run here; only their size is reported.
+	sizeOnly bool
+}
+
+var gcflagConfigs = []config{
+	{name: "default"},
+	{name: "nobounds", gcflags: "-B"},
+	{name: "noinline", gcflags: "-l"},
+	{name: "bce", gcflags: "-d=ssa/check_bce", reportOnly: true},
+}
+
+var goamd64Configs = []config{
+	{name: "v1", env: []string{"GOARCH=amd64", "GOAMD64=v1"}},
+	{name: "v2", env: []string{"GOARCH=amd64", "GOAMD64=v2"}},
+	{name: "v3", env: []string{"GOARCH=amd64", "GOAMD64=v3"}},
+	{name: "v4", env: []string{"GOARCH=amd64", "GOAMD64=v4"}},
+}
+
+var arm64Config = config{name: "arm64", env: []string{"GOOS=linux", "GOARCH=arm64"}, sizeOnly: true}
+
+// What one benchmark got under one config.
+type result struct {
+	time   time.Duration
+	size   int64
+	checks int
+	// Set when the binary could not run, e.g. GOAMD64=v4 on a CPU
+	// without AVX-512; the build itself succeeded.
+	runErr error
+}
+
+type benchmark struct {
+	name string
+	src  string
+}
+
+// Benchmarks are the .go files in dir that define main; the others are
+// shared sources linked into every benchmark, as the Makefile does,
+// except _test.go files.
+func discover(dir string) ([]benchmark, []string, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return nil, nil, err
+	}
+	var benches []benchmark
+	var shared []string
+	for _, f := range files {
+		// Tests build with go test against one benchmark, never into it
+		if strings.HasSuffix(f, "_test.go") {
+			continue
+		}
+		src, err := os.ReadFile(f)
+		if err != nil {
+			return nil, nil, err
+		}
+		if bytes.Contains(src, []byte("\nfunc main() {")) {
+			benches = append(benches, benchmark{strings.TrimSuffix(filepath.Base(f), ".go"), f})
+		} else {
+			shared = append(shared, f)
+		}
+	}
+	return benches, shared, nil
+}
+
+// Builds b under c into outDir and returns the binary path and the
+// compiler's diagnostic output.
+func build(b benchmark, shared []string, c config, outDir string) (string, string, error) {
+	bin := filepath.Join(outDir, b.name+"-"+c.name)
+	args := []string{"build", "-o", bin}
+	if c.gcflags != "" {
+		args = append(args, "-gcflags="+c.gcflags)
+	}
+	args = append(args, b.src)
+	args = append(args, shared...)
+
+	cmd := exec.Command("go", args...)
+	cmd.Env = append(os.Environ(), c.env...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	if err != nil {
+		return "", stderr.String(), fmt.Errorf("go build %s (%s): %v\n%s", b.name, c.name, err, stderr.String())
+	}
+	return bin, stderr.String(), nil
+}
+
+// Bounds checks left in the benchmark's own file, per -d=ssa/check_bce;
+// the shared sources are the same for everyone.
+func countBoundsChecks(diag, src string) int {
+	// The compiler prints paths relative to the working directory, so
+	// "./sieve.go" or "../benchmark/sieve.go" depending on -dir; match
+	// the file name of the position, which is unique among the sources.
+	base := filepath.Base(src)
+	n := 0
+	for _, line := range strings.Split(diag, "\n") {
+		file, _, ok := strings.Cut(line, ":")
+		if !ok || filepath.Base(file) != base {
+			continue
+		}
+		if strings.Contains(line, "Found IsInBounds") || strings.Contains(line, "Found IsSliceInBounds") {
+			n++
+		}
+	}
+	return n
+}
+
+var mmlbenchNs = regexp.MustCompile(`^MMLBENCH .*\bns=(\d+)\b`)
+
+// Runs bin once and returns its kernel time: the sum of the ns fields
+// of its MMLBENCH lines, or wall time if it printed none.
+func runOnce(bin string, args []string) (time.Duration, error) {
+	cmd := exec.Command(bin, args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	start := time.Now()
+	if err := cmd.Run(); err != nil {
+		return 0, fmt.Errorf("%s: %v\n%s", bin, err, stderr.String())
+	}
+	wall := time.Since(start)
+
+	var total time.Duration
+	found := false
+	sc := bufio.NewScanner(&stderr)
+	for sc.Scan() {
+		if m := mmlbenchNs.FindStringSubmatch(sc.Text()); m != nil {
+			ns, _ := strconv.ParseInt(m[1], 10, 64)
+			total += time.Duration(ns)
+			found = true
+		}
+	}
+	if !found {
+		return wall, nil
+	}
+	return total, nil
+}
+
+func median(ds []time.Duration) time.Duration {
+	s := slices.Clone(ds)
+	slices.Sort(s)
+	return s[len(s)/2]
+}
+
+func delta[T time.Duration | int64](v, base T) string {
+	if base == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%+.1f%%", 100*(float64(v)-float64(base))/float64(base))
+}
+
+// Builds and runs b under every config. A build failure stops the
+// benchmark; a run failure is recorded against that config only.
+func measure(b benchmark, shared []string, cs []config, outDir string, runs int, args []string) (map[string]result, error) {
+	results := map[string]result{}
+	for _, c := range cs {
+		bin, diag, err := build(b, shared, c, outDir)
+		if err != nil {
+			return nil, err
+		}
+		var r result
+		if fi, err := os.Stat(bin); err == nil {
+			r.size = fi.Size()
+		}
+		switch {
+		case c.reportOnly:
+			r.checks = countBoundsChecks(diag, b.src)
+		case c.sizeOnly:
+		default:
+			samples := make([]time.Duration, 0, runs)
+			for i := 0; i < runs; i++ {
+				t, err := runOnce(bin, args)
+				if err != nil {
+					r.runErr = err
+					break
+				}
+				samples = append(samples, t)
+			}
+			if r.runErr == nil {
+				r.time = median(samples)
+			}
+		}
+		results[c.name] = r
+	}
+	return results, nil
+}
+
+// The host, so a table can be read without knowing where it came from.
+func describeHost() string {
+	cpu := "unknown cpu"
+	if info, err := os.ReadFile("/proc/cpuinfo"); err == nil {
+		for _, line := range strings.Split(string(info), "\n") {
+			if name, ok := strings.CutPrefix(line, "model name"); ok {
+				cpu = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(name), ":"))
+				break
+			}
+		}
+	}
+	return fmt.Sprintf("# %s %s/%s, %s, %d CPUs", runtime.Version(), runtime.GOOS, runtime.GOARCH, cpu, runtime.NumCPU())
+}
+
+func printGcflags(tw *tabwriter.Writer, name string, rs map[string]result) bool {
+	for _, c := range gcflagConfigs {
+		if err := rs[c.name].runErr; err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			fmt.Fprintf(tw, "%s\tfailed\n", name)
+			return false
+		}
+	}
+	base := rs["default"].time
+	fmt.Fprintf(tw, "%s\t%v\t%v\t%s\t%v\t%s\t%d\n", name,
+		base.Round(time.Microsecond),
+		rs["nobounds"].time.Round(time.Microsecond), delta(rs["nobounds"].time, base),
+		rs["noinline"].time.Round(time.Microsecond), delta(rs["noinline"].time, base),
+		rs["bce"].checks)
+	return true
+}
+
+// One row per build, deltas against v1. A level the CPU lacks shows up
+// as "can't run" rather than failing the sweep.
+func printGoamd64(tw *tabwriter.Writer, name string, cs []config, rs map[string]result) bool {
+	base := rs["v1"]
+	for _, c := range cs {
+		r := rs[c.name]
+		switch {
+		case c.sizeOnly:
+			fmt.Fprintf(tw, "%s\t%s\t-\t\t%d\t%s\n", name, c.name, r.size, delta(r.size, base.size))
+		case r.runErr != nil:
+			fmt.Fprintf(tw, "%s\t%s\tcan't run\t\t%d\t%s\n", name, c.name, r.size, delta(r.size, base.size))
+		default:
+			fmt.Fprintf(tw, "%s\t%s\t%v\t%s\t%d\t%s\n", name, c.name,
+				r.time.Round(time.Microsecond), delta(r.time, base.time), r.size, delta(r.size, base.size))
+		}
+	}
+	return base.runErr == nil
+}
+
+func main() {
+	dir := flag.String("dir", ".", "benchmark directory")
+	outDir := flag.String("o", "build/matrix", "where the builds go")
+	filter := flag.String("filter", "", "only benchmarks matching this regexp")
+	skip := flag.String("skip", "^lines$", "skip benchmarks matching this regexp (lines reads stdin)")
+	runs := flag.Int("runs", 3, "runs per build; the median is reported")
+	benchArgs := flag.String("args", "-size small", "arguments passed to every benchmark")
+	mode := flag.String("mode", "gcflags", "gcflags (default, -B, -l, bounds checks) or goamd64 (v1..v4)")
+	arm64 := flag.Bool("arm64", false, "with -mode goamd64, also cross-build for arm64 and report its size")
+	flag.Parse()
+
+	var cs []config
+	switch *mode {
+	case "gcflags":
+		cs = gcflagConfigs
+	case "goamd64":
+		if runtime.GOARCH != "amd64" {
+			fmt.Fprintln(os.Stderr, "-mode goamd64 needs an amd64 host to run the builds")
+			os.Exit(2)
+		}
+		cs = goamd64Configs
+		if *arm64 {
+			cs = append(slices.Clone(cs), arm64Config)
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "unknown mode %q\n", *mode)
+		os.Exit(2)
+	}
+
+	if *runs < 1 {
+		fmt.Fprintln(os.Stderr, "-runs must be at least 1")
+		os.Exit(2)
+	}
+	only, err := regexp.Compile(*filter)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	skipRe, err := regexp.Compile(*skip)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	benches, shared, err := discover(*dir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if err := os.MkdirAll(*outDir, 0o755); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	args := strings.Fields(*benchArgs)
+
+	fmt.Println(describeHost())
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	if *mode == "gcflags" {
+		fmt.Fprintln(tw, "benchmark\tdefault\t-B\tdelta\t-l\tdelta\tbounds checks")
+	} else {
+		fmt.Fprintln(tw, "benchmark\tbuild\tkernel\tdelta\tbytes\tdelta")
+	}
+
+	failed := false
+	for _, b := range benches {
+		if !only.MatchString(b.name) || (*skip != "" && skipRe.MatchString(b.name)) {
+			continue
+		}
+		fmt.Fprintf(os.Stderr, "%s...\n", b.name)
+
+		rs, err := measure(b, shared, cs, *outDir, *runs, args)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			fmt.Fprintf(tw, "%s\tfailed\n", b.name)
+			failed = true
+			continue
+		}
+		var ok bool
+		if *mode == "gcflags" {
+			ok = printGcflags(tw, b.name, rs)
+		} else {
+			ok = printGoamd64(tw, b.name, cs, rs)
+		}
+		if !ok {
+			failed = true
+		}
+	}
+	tw.Flush()
+
+	if failed {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Steps to reach 1: n -> n/2 if even, 3n+1 if odd.
+func steps(n int64) int64 {
+	var count int64 = 0
+	for n != 1 {
+		if n&1 == 0 {
+			n >>= 1
+		} else {
+			n = 3*n + 1
+		}
+		count++
+	}
+	return count
+}
+
+// Longest chain for starts in [1, limit), recomputing every chain.
+func longestPlain(limit int64) (int64, int64) {
+	var best, bestSteps int64 = 1, 0
+	for start := int64(1); start < limit; start++ {
+		if s := steps(start); s > bestSteps {
+			best, bestSteps = start, s
+		}
+	}
+	return best, bestSteps
+}
+
+// Same search, but chain lengths below limit are cached: each walk stops
+// as soon as it drops to a value whose length is already known.
+func longestMemo(limit int64) (int64, int64) {
+	cache := make([]int64, limit)
+	var best, bestSteps int64 = 1, 0
+	for start := int64(2); start < limit; start++ {
+		n := start
+		var count int64 = 0
+		for n >= start {
+			if n&1 == 0 {
+				n >>= 1
+			} else {
+				n = 3*n + 1
+			}
+			count++
+		}
+		// n < start, so its length was filled in an earlier iteration
+		s := count + cache[n]
+		cache[start] = s
+		if s > bestSteps {
+			best, bestSteps = start, s
+		}
+	}
+	return best, bestSteps
+}
+
+var presets = sizePresets{
+	"small":  {"n": "500000"},
+	"medium": {"n": "5000000"},
+	"large":  {"n": "50000000"},
+	"huge":   {"n": "200000000"},
+}
+
+func main() {
+	limit := flag.Int64("n", 5_000_000, "search starting values below this")
+	variant := flag.String("variant", "plain", "plain or memo")
+	parseWithSize(presets)
+	defer startProfiling()()
+
+	if *limit < 2 {
+		fmt.Fprintln(os.Stderr, "-n must be at least 2")
+		exit(2)
+	}
+
+	var longest func(int64) (int64, int64)
+	switch *variant {
+	case "plain":
+		longest = longestPlain
+	case "memo":
+		longest = longestMemo
+	default:
+		fmt.Fprintf(os.Stderr, "unknown variant %q\n", *variant)
+		exit(2)
+	}
+
+	// Both variants must agree on a small prefix before the real run
+	check := min(*limit, 100_000)
+	p, ps := longestPlain(check)
+	m, ms := longestMemo(check)
+	if p != m || ps != ms {
+		fmt.Fprintf(os.Stderr, "plain and memo disagree below %d: %d (%d) vs %d (%d)\n", check, p, ps, m, ms)
+		exit(1)
+	}
+
+	begin := startKernel()
+	start, length := longest(*limit)
+	elapsed := time.Since(begin)
+	fmt.Printf("Longest Collatz chain below %d (%s): start %d, %d steps\n", *limit, *variant, start, length)
+	reportKernel("collatz", *variant, elapsed, start)
+}
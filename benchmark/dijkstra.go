@@ -0,0 +1,186 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+)
+
+const maxWeight = 100
+
+// Directed graph in CSR form: the out-edges of u are
+// dst[offsets[u]:offsets[u+1]] with matching weights.
+type graph struct {
+	offsets []int64
+	dst     []int64
+	weight  []int64
+}
+
+// Every node gets `degree` random out-edges plus a ring edge to u+1,
+// which keeps the whole graph reachable from node 0.
+func buildGraph(n, degree int, seed int64) *graph {
+	r := newLCG(seed)
+	per := degree + 1
+	g := &graph{
+		offsets: make([]int64, n+1),
+		dst:     make([]int64, n*per),
+		weight:  make([]int64, n*per),
+	}
+	for u := 0; u < n; u++ {
+		base := u * per
+		g.offsets[u] = int64(base)
+		for e := 0; e < degree; e++ {
+			g.dst[base+e] = r.below(int64(n))
+			g.weight[base+e] = 1 + r.below(maxWeight)
+		}
+		g.dst[base+degree] = int64((u + 1) % n)
+		g.weight[base+degree] = maxWeight
+	}
+	g.offsets[n] = int64(n * per)
+	return g
+}
+
+// Binary min-heap of (distance, node) pairs in two parallel arrays.
+// No decrease-key: stale entries are pushed again and skipped on pop.
+type heap struct {
+	dist []int64
+	node []int64
+}
+
+func (h *heap) push(d, v int64) {
+	h.dist = append(h.dist, d)
+	h.node = append(h.node, v)
+	i := len(h.dist) - 1
+	for i > 0 {
+		parent := (i - 1) / 2
+		if h.dist[parent] <= h.dist[i] {
+			break
+		}
+		h.swap(i, parent)
+		i = parent
+	}
+}
+
+func (h *heap) pop() (int64, int64) {
+	d, v := h.dist[0], h.node[0]
+	last := len(h.dist) - 1
+	h.swap(0, last)
+	h.dist = h.dist[:last]
+	h.node = h.node[:last]
+
+	i := 0
+	for {
+		smallest := i
+		l, r := 2*i+1, 2*i+2
+		if l < last && h.dist[l] < h.dist[smallest] {
+			smallest = l
+		}
+		if r < last && h.dist[r] < h.dist[smallest] {
+			smallest = r
+		}
+		if smallest == i {
+			break
+		}
+		h.swap(i, smallest)
+		i = smallest
+	}
+	return d, v
+}
+
+func (h *heap) swap(a, b int) {
+	h.dist[a], h.dist[b] = h.dist[b], h.dist[a]
+	h.node[a], h.node[b] = h.node[b], h.node[a]
+}
+
+func dijkstra(g *graph, src int64) []int64 {
+	n := len(g.offsets) - 1
+	const inf = int64(1) << 62
+	dist := make([]int64, n)
+	for i := range dist {
+		dist[i] = inf
+	}
+	dist[src] = 0
+
+	h := &heap{}
+	h.push(0, src)
+	for len(h.dist) > 0 {
+		d, u := h.pop()
+		if d > dist[u] {
+			continue
+		}
+		for e := g.offsets[u]; e < g.offsets[u+1]; e++ {
+			v := g.dst[e]
+			nd := d + g.weight[e]
+			if nd < dist[v] {
+				dist[v] = nd
+				h.push(nd, v)
+			}
+		}
+	}
+	return dist
+}
+
+// Checks dist is exactly the shortest-path function from src:
+// no edge can relax it further, and every other node has an
+// incoming edge that is tight, i.e. a witness for its distance.
+func verify(g *graph, dist []int64, src int64) bool {
+	n := len(dist)
+	tight := make([]bool, n)
+	tight[src] = dist[src] == 0
+	for u := 0; u < n; u++ {
+		for e := g.offsets[u]; e < g.offsets[u+1]; e++ {
+			v := g.dst[e]
+			nd := dist[u] + g.weight[e]
+			if nd < dist[v] {
+				return false
+			}
+			if nd == dist[v] && v != src {
+				tight[v] = true
+			}
+		}
+	}
+	for _, t := range tight {
+		if !t {
+			return false
+		}
+	}
+	return true
+}
+
+var presets = sizePresets{
+	"small":  {"n": "100000"},
+	"medium": {"n": "1000000"},
+	"large":  {"n": "4000000"},
+	"huge":   {"n": "16000000"},
+}
+
+func main() {
+	n := flag.Int("n", 1_000_000, "number of nodes")
+	degree := flag.Int("degree", 4, "random out-edges per node")
+	seed := flag.Int64("seed", 42, "LCG seed for the graph")
+	parseWithSize(presets)
+	defer startProfiling()()
+
+	if *n < 1 {
+		fmt.Fprintln(os.Stderr, "-n must be at least 1")
+		exit(2)
+	}
+
+	g := buildGraph(*n, *degree, *seed)
+	start := startKernel()
+	dist := dijkstra(g, 0)
+	elapsed := time.Since(start)
+
+	if !verify(g, dist, 0) {
+		fmt.Fprintln(os.Stderr, "distances are not shortest paths")
+		exit(1)
+	}
+
+	var total int64 = 0
+	for _, d := range dist {
+		total += d
+	}
+	fmt.Printf("Dijkstra distance sum: %d\n", total)
+	reportKernel("dijkstra", defaultVariant, elapsed, total)
+}
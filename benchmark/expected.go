@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// Known-good results, keyed by benchmark and the parameters that decide
+// them. -verify checks a run against this table instead of trusting
+// whoever last eyeballed the output. Where an independent source exists
+// it was used (prime counts, OEIS A000170 for n-queens, the closed form
+// A(3, n) = 2^(n+3) - 3). Matmul traces and the quicksort median come
+// from a Python replay of the LCG; the trace only needs the diagonal,
+// sum over i, k of A[i][k]*B[k][i], so that check is O(n^2).
+//
+// Params are written the way verifyResult callers format them:
+// space-separated key=value pairs in a fixed order.
+
+type expectKey struct {
+	bench  string
+	params string
+}
+
+var expectedResults = map[expectKey]int64{
+	// Primes up to limit
+	{"sieve", "limit=1000000"}:    78498,
+	{"sieve", "limit=10000000"}:   664579,
+	{"sieve", "limit=100000000"}:  5761455,
+	{"sieve", "limit=1000000000"}: 50847534,
+
+	// Solutions on an n x n board
+	{"nqueens", "n=8"}:  92,
+	{"nqueens", "n=9"}:  352,
+	{"nqueens", "n=10"}: 724,
+	{"nqueens", "n=11"}: 2680,
+	{"nqueens", "n=12"}: 14200,
+	{"nqueens", "n=13"}: 73712,
+	{"nqueens", "n=14"}: 365596,
+	{"nqueens", "n=15"}: 2279184,
+
+	// Trace of A*B, A and B filled from the LCG mod 100
+	{"matmul", "n=100 seeds=42,1337"}:  376324,
+	{"matmul", "n=200 seeds=42,1337"}:  -889832,
+	{"matmul", "n=500 seeds=42,1337"}:  381460,
+	{"matmul", "n=1000 seeds=42,1337"}: 316908,
+	{"matmul", "n=2000 seeds=42,1337"}: 6964180,
+
+	{"ackermann", "m=3 n=8"}:  2045,
+	{"ackermann", "m=3 n=9"}:  4093,
+	{"ackermann", "m=3 n=10"}: 8189,
+	{"ackermann", "m=3 n=11"}: 16381,
+	{"ackermann", "m=3 n=12"}: 32765,
+
+	// Median of quicksort.mml's input once sorted (sort.go -parity)
+	{"quicksort", "n=1000000 seed=42"}: -85,
+
+	// Hits of the serial variant, which every parallel run must match
+	// exactly; the small one also from a Python replay
+	{"montecarlo-pi", "n=20000000 seed=42"}:   15707880,
+	{"montecarlo-pi", "n=200000000 seed=42"}:  157074776,
+	{"montecarlo-pi", "n=1000000000 seed=42"}: 785396176,
+
+	// Sum of the fused loop; channels must agree at any buffer size
+	{"pipeline", "n=500000 seed=42"}:    166216766162,
+	{"pipeline", "n=5000000 seed=42"}:   1664364853080,
+	{"pipeline", "n=20000000 seed=42"}:  6656231395768,
+	{"pipeline", "n=100000000 seed=42"}: 33293772195515,
+}
+
+// verifyResult exits 1 if got differs from the table and 2 if the table
+// has nothing for these parameters; a run that can't be checked should
+// not pass as verified.
+func verifyResult(bench, params string, got int64) {
+	want, ok := expectedResults[expectKey{bench, params}]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "verify: no expected result for %s %s\n", bench, params)
+		exit(2)
+	}
+	if got != want {
+		fmt.Fprintf(os.Stderr, "verify: %s %s: got %d, want %d\n", bench, params, got, want)
+		exit(1)
+	}
+	fmt.Fprintf(os.Stderr, "verify: %s %s ok\n", bench, params)
+}
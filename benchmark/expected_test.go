@@ -0,0 +1,83 @@
+package main
+
+import (
+	"cmp"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// The expectedResults entries of one benchmark, for tests that run its
+// kernel at every registered size. Built into every _test.go set; see
+// make test.
+
+// One entry, with its params split back into key=value.
+type expectedCase struct {
+	params string
+	values map[string]string
+	size   int64 // the size param, to order and skip by
+	want   int64
+}
+
+func (c expectedCase) int(t *testing.T, key string) int64 {
+	t.Helper()
+	v, err := strconv.ParseInt(c.values[key], 10, 64)
+	if err != nil {
+		t.Fatalf("%s: %s: %v", c.params, key, err)
+	}
+	return v
+}
+
+// Comma-separated values, like matmul's seeds=42,1337.
+func (c expectedCase) ints(t *testing.T, key string) []int64 {
+	t.Helper()
+	var out []int64
+	for _, s := range strings.Split(c.values[key], ",") {
+		v, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			t.Fatalf("%s: %s: %v", c.params, key, err)
+		}
+		out = append(out, v)
+	}
+	return out
+}
+
+// testExpected runs every entry for bench as a subtest named by its
+// params and compares what run returns with the table. The big ones take
+// minutes and gigabytes, so under -short only entries whose size param
+// is at most shortMax run.
+func testExpected(t *testing.T, bench, size string, shortMax int64, run func(t *testing.T, c expectedCase) int64) {
+	var cases []expectedCase
+	for k, want := range expectedResults {
+		if k.bench != bench {
+			continue
+		}
+		c := expectedCase{params: k.params, values: map[string]string{}, want: want}
+		for _, kv := range strings.Fields(k.params) {
+			key, value, _ := strings.Cut(kv, "=")
+			c.values[key] = value
+		}
+		c.size, _ = strconv.ParseInt(c.values[size], 10, 64)
+		cases = append(cases, c)
+	}
+	if len(cases) == 0 {
+		t.Fatalf("expected.go has no entries for %s", bench)
+	}
+	slices.SortFunc(cases, func(a, b expectedCase) int {
+		if a.size != b.size {
+			return cmp.Compare(a.size, b.size)
+		}
+		return strings.Compare(a.params, b.params)
+	})
+	for _, c := range cases {
+		t.Run(c.params, func(t *testing.T) {
+			if testing.Short() && c.size > shortMax {
+				t.Skipf("%s=%s is too big for -short", size, c.values[size])
+			}
+			if got := run(t, c); got != c.want {
+				t.Errorf("got %d, want %d", got, c.want)
+			}
+		})
+	}
+}
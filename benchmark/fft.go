@@ -0,0 +1,182 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"time"
+)
+
+// Complex values are stored interleaved: data[2k] is the real part of
+// element k and data[2k+1] the imaginary part. No complex128, so the
+// kernel maps directly onto plain float arrays.
+
+// Reorders the elements so the butterflies can run in place.
+func bitReverse(data []float64, n int) {
+	j := 0
+	for i := 0; i < n-1; i++ {
+		if i < j {
+			data[2*i], data[2*j] = data[2*j], data[2*i]
+			data[2*i+1], data[2*j+1] = data[2*j+1], data[2*i+1]
+		}
+		bit := n >> 1
+		for j&bit != 0 {
+			j ^= bit
+			bit >>= 1
+		}
+		j |= bit
+	}
+}
+
+// Twiddle k is e^(-2*pi*i*k/n), for k < n/2.
+func twiddles(n int) ([]float64, []float64) {
+	wr := make([]float64, n/2)
+	wi := make([]float64, n/2)
+	for k := range wr {
+		angle := -2 * math.Pi * float64(k) / float64(n)
+		wr[k] = math.Cos(angle)
+		wi[k] = math.Sin(angle)
+	}
+	return wr, wi
+}
+
+// Iterative radix-2 Cooley-Tukey. n must be a power of two.
+// The inverse transform conjugates the twiddles and scales by 1/n.
+func fft(data []float64, n int, wr, wi []float64, inverse bool) {
+	bitReverse(data, n)
+
+	sign := 1.0
+	if inverse {
+		sign = -1.0
+	}
+
+	for size := 2; size <= n; size <<= 1 {
+		half := size / 2
+		stride := n / size
+		for start := 0; start < n; start += size {
+			for k := 0; k < half; k++ {
+				tr := wr[k*stride]
+				ti := sign * wi[k*stride]
+
+				a := 2 * (start + k)
+				b := 2 * (start + k + half)
+				// t = w * data[b]
+				xr := data[b]*tr - data[b+1]*ti
+				xi := data[b]*ti + data[b+1]*tr
+
+				data[b] = data[a] - xr
+				data[b+1] = data[a+1] - xi
+				data[a] += xr
+				data[a+1] += xi
+			}
+		}
+	}
+
+	if inverse {
+		scale := 1 / float64(n)
+		for i := range data {
+			data[i] *= scale
+		}
+	}
+}
+
+// O(n^2) reference DFT, only used on a small input.
+func dft(data []float64, n int) []float64 {
+	out := make([]float64, 2*n)
+	for k := 0; k < n; k++ {
+		for j := 0; j < n; j++ {
+			angle := -2 * math.Pi * float64(j*k%n) / float64(n)
+			c, s := math.Cos(angle), math.Sin(angle)
+			out[2*k] += data[2*j]*c - data[2*j+1]*s
+			out[2*k+1] += data[2*j]*s + data[2*j+1]*c
+		}
+	}
+	return out
+}
+
+func energy(data []float64) float64 {
+	var acc float64 = 0
+	for _, v := range data {
+		acc += v * v
+	}
+	return acc
+}
+
+var presets = sizePresets{
+	"small":  {"logn": "16"},
+	"medium": {"logn": "20"},
+	"large":  {"logn": "22"},
+	"huge":   {"logn": "24"},
+}
+
+func main() {
+	logN := flag.Int("logn", 20, "transform size as a power of two")
+	reps := flag.Int("reps", 4, "forward+inverse round trips")
+	seed := flag.Int64("seed", 42, "LCG seed for the input")
+	parseWithSize(presets)
+	defer startProfiling()()
+
+	if *logN < 1 || *logN > 30 {
+		fmt.Fprintln(os.Stderr, "-logn must be between 1 and 30")
+		exit(2)
+	}
+	n := 1 << *logN
+
+	// Check the forward transform against the naive DFT first
+	small := make([]float64, 2*64)
+	sr := newLCG(*seed + 1)
+	for i := range small {
+		small[i] = sr.float()*2 - 1
+	}
+	want := dft(small, 64)
+	swr, swi := twiddles(64)
+	fft(small, 64, swr, swi, false)
+	for i := range small {
+		if math.Abs(small[i]-want[i]) > 1e-9 {
+			fmt.Fprintf(os.Stderr, "FFT diverges from the reference DFT at %d\n", i/2)
+			exit(1)
+		}
+	}
+
+	original := make([]float64, 2*n)
+	r := newLCG(*seed)
+	for i := range original {
+		original[i] = r.float()*2 - 1
+	}
+	data := make([]float64, 2*n)
+	copy(data, original)
+
+	wr, wi := twiddles(n)
+	inputEnergy := energy(original)
+
+	var spectrumEnergy float64
+	start := startKernel()
+	for rep := 0; rep < *reps; rep++ {
+		fft(data, n, wr, wi, false)
+		spectrumEnergy = energy(data)
+		fft(data, n, wr, wi, true)
+	}
+	elapsed := time.Since(start)
+
+	// Parseval: the spectrum carries n times the energy of the signal
+	if *reps > 0 {
+		rel := math.Abs(spectrumEnergy/float64(n)-inputEnergy) / inputEnergy
+		if rel > 1e-9 {
+			fmt.Fprintf(os.Stderr, "spectrum energy off by %.3e (relative)\n", rel)
+			exit(1)
+		}
+	}
+
+	var maxErr float64 = 0
+	for i := range data {
+		maxErr = max(maxErr, math.Abs(data[i]-original[i]))
+	}
+	if maxErr > 1e-9 {
+		fmt.Fprintf(os.Stderr, "round trip error %.3e exceeds tolerance\n", maxErr)
+		exit(1)
+	}
+
+	fmt.Printf("FFT round trips: %d, size: %d, spectrum energy: %.6f\n", *reps, n, spectrumEnergy)
+	reportKernel("fft", defaultVariant, elapsed, fmt.Sprintf("%.6f", spectrumEnergy))
+}
@@ -1,21 +1,45 @@
 package main
 
-import "fmt"
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
 
-func fizzbuzz(n int) {
+func fizzbuzz(n int, w io.Writer) {
 	for i := 1; i <= n; i++ {
 		if i%15 == 0 {
-			fmt.Println("FizzBuzz")
+			fmt.Fprintln(w, "FizzBuzz")
 		} else if i%3 == 0 {
-			fmt.Println("Fizz")
+			fmt.Fprintln(w, "Fizz")
 		} else if i%5 == 0 {
-			fmt.Println("Buzz")
+			fmt.Fprintln(w, "Buzz")
 		} else {
-			fmt.Println(i)
+			fmt.Fprintln(w, i)
 		}
 	}
 }
 
+var presets = sizePresets{
+	"small":  {"n": "1000000"},
+	"medium": {"n": "10000000"},
+	"large":  {"n": "100000000"},
+	"huge":   {"n": "1000000000"},
+}
+
 func main() {
-	fizzbuzz(10000000)
+	n := flag.Int("n", 10000000, "count up to this")
+	checks := flag.Int("check", 0, "run this many randomized checks against a reference formatter instead of the benchmark")
+	parseWithSize(presets)
+	defer startProfiling()()
+
+	if *checks > 0 {
+		runChecks("fizzbuzz", *checks, checkFizzbuzz(fizzbuzz))
+	}
+
+	start := startKernel()
+	fizzbuzz(*n, os.Stdout)
+	reportKernel("fizzbuzz", "println", time.Since(start), *n)
 }
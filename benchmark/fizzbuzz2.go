@@ -2,11 +2,18 @@ package main
 
 import (
 	"bufio"
+	"flag"
+	"io"
 	"os"
 	"strconv"
+	"time"
 )
 
-func fizzbuzz(n int, w *bufio.Writer) {
+// Same lines as fizzbuzz.go, written through one buffer that's
+// flushed at the end.
+func fizzbuzz(n int, out io.Writer) {
+	w := bufio.NewWriter(out)
+	defer w.Flush()
 	for i := 1; i <= n; i++ {
 		if i%15 == 0 {
 			w.WriteString("FizzBuzz\n")
@@ -21,8 +28,24 @@ func fizzbuzz(n int, w *bufio.Writer) {
 	}
 }
 
+var presets = sizePresets{
+	"small":  {"n": "1000000"},
+	"medium": {"n": "10000000"},
+	"large":  {"n": "100000000"},
+	"huge":   {"n": "1000000000"},
+}
+
 func main() {
-	w := bufio.NewWriter(os.Stdout)
-	fizzbuzz(10000000, w)
-	w.Flush()
+	n := flag.Int("n", 10000000, "count up to this")
+	checks := flag.Int("check", 0, "run this many randomized checks against a reference formatter instead of the benchmark")
+	parseWithSize(presets)
+	defer startProfiling()()
+
+	if *checks > 0 {
+		runChecks("fizzbuzz2", *checks, checkFizzbuzz(fizzbuzz))
+	}
+
+	start := startKernel()
+	fizzbuzz(*n, os.Stdout)
+	reportKernel("fizzbuzz", "buffered", time.Since(start), *n)
 }
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// Builds against either fizzbuzz, e.g.
+//
+//	go test fizzbuzz2.go fizzbuzz_test.go $(GO_SHARED)
+
+func TestFizzbuzzChecks(t *testing.T) {
+	check := checkFizzbuzz(fizzbuzz)
+	for i := 0; i < 200; i++ {
+		if err := check(i, newLCG(streamSeed(checkSeed, i))); err != nil {
+			t.Fatalf("case %d: %v", i, err)
+		}
+	}
+}
+
+// One line per number; a number's line says Fizz exactly when 3 divides
+// it and Buzz exactly when 5 does, and any other line is the number.
+func TestFizzbuzzLines(t *testing.T) {
+	const n = 3000
+	var out bytes.Buffer
+	fizzbuzz(n, &out)
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(lines) != n {
+		t.Fatalf("got %d lines, want %d", len(lines), n)
+	}
+	for i, line := range lines {
+		k := i + 1
+		fizz, buzz := strings.HasPrefix(line, "Fizz"), strings.HasSuffix(line, "Buzz")
+		if fizz != (k%3 == 0) || buzz != (k%5 == 0) {
+			t.Fatalf("line %d is %q", k, line)
+		}
+		if !fizz && !buzz && line != strconv.Itoa(k) {
+			t.Fatalf("line %d is %q", k, line)
+		}
+	}
+}
+
+func FuzzFizzbuzz(f *testing.F) {
+	for _, n := range []int{0, 1, 3, 5, 14, 15, 16, 100} {
+		f.Add(n)
+	}
+	f.Fuzz(func(t *testing.T, n int) {
+		if n < 0 || n > 20_000 {
+			t.Skip()
+		}
+		if err := checkFizzbuzzN(fizzbuzz, n); err != nil {
+			t.Error(err)
+		}
+	})
+}
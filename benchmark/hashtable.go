@@ -0,0 +1,152 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Open-addressing hash table with linear probing.
+// Two flat arrays, power-of-two capacity, key 0 marks an empty slot.
+// No deletes, no resizing: the benchmark sizes it up front.
+type table struct {
+	keys  []uint64
+	vals  []int64
+	shift uint
+	mask  uint64
+}
+
+func newTable(n int) *table {
+	// Keep the load factor at or below 1/2
+	capacity := 1
+	bits := uint(0)
+	for capacity < 2*n {
+		capacity <<= 1
+		bits++
+	}
+	return &table{
+		keys:  make([]uint64, capacity),
+		vals:  make([]int64, capacity),
+		shift: 64 - bits,
+		mask:  uint64(capacity - 1),
+	}
+}
+
+// Fibonacci hashing: multiply by 2^64/phi and keep the top bits.
+func (t *table) slot(key uint64) uint64 {
+	return (key * 0x9E3779B97F4A7C15) >> t.shift
+}
+
+func (t *table) put(key uint64, val int64) {
+	i := t.slot(key)
+	for {
+		k := t.keys[i]
+		if k == 0 || k == key {
+			t.keys[i] = key
+			t.vals[i] = val
+			return
+		}
+		i = (i + 1) & t.mask
+	}
+}
+
+func (t *table) get(key uint64) (int64, bool) {
+	i := t.slot(key)
+	for {
+		k := t.keys[i]
+		if k == key {
+			return t.vals[i], true
+		}
+		if k == 0 {
+			return 0, false
+		}
+		i = (i + 1) & t.mask
+	}
+}
+
+// splitmix64 finalizer. It is a bijection, so distinct inputs give
+// distinct keys, and it only maps 0 to 0.
+func mix(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xBF58476D1CE4E5B9
+	x ^= x >> 27
+	x *= 0x94D049BB133111EB
+	x ^= x >> 31
+	return x
+}
+
+var presets = sizePresets{
+	"small":  {"n": "200000", "lookups": "1000000"},
+	"medium": {"n": "2000000", "lookups": "10000000"},
+	"large":  {"n": "8000000", "lookups": "40000000"},
+	"huge":   {"n": "32000000", "lookups": "160000000"},
+}
+
+func main() {
+	n := flag.Int("n", 2_000_000, "number of keys to insert")
+	lookups := flag.Int("lookups", 10_000_000, "number of lookups")
+	seed := flag.Int64("seed", 42, "LCG seed for the queries")
+	variant := flag.String("variant", "linear", "linear or map")
+	parseWithSize(presets)
+	defer startProfiling()()
+
+	if *n < 1 {
+		fmt.Fprintln(os.Stderr, "-n must be at least 1")
+		exit(2)
+	}
+
+	// Key i is mix(i+1). Queries draw i from [0, 2n), so a query hits
+	// exactly when i < n, and the value found is i.
+	queries := make([]uint64, *lookups)
+	r := newLCG(*seed)
+	var expectedHits, expectedSum int64 = 0, 0
+	for j := range queries {
+		i := r.below(int64(2 * *n))
+		queries[j] = mix(uint64(i + 1))
+		if i < int64(*n) {
+			expectedHits++
+			expectedSum += i
+		}
+	}
+
+	var hits, valSum int64 = 0, 0
+	start := startKernel()
+	switch *variant {
+	case "linear":
+		t := newTable(*n)
+		for i := 0; i < *n; i++ {
+			t.put(mix(uint64(i+1)), int64(i))
+		}
+		for _, q := range queries {
+			if v, ok := t.get(q); ok {
+				hits++
+				valSum += v
+			}
+		}
+	case "map":
+		m := make(map[uint64]int64, *n)
+		for i := 0; i < *n; i++ {
+			m[mix(uint64(i+1))] = int64(i)
+		}
+		for _, q := range queries {
+			if v, ok := m[q]; ok {
+				hits++
+				valSum += v
+			}
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "unknown variant %q\n", *variant)
+		exit(2)
+	}
+	elapsed := time.Since(start)
+
+	if hits != expectedHits || valSum != expectedSum {
+		fmt.Fprintf(os.Stderr, "%s: got %d hits (sum %d), expected %d (sum %d)\n",
+			*variant, hits, valSum, expectedHits, expectedSum)
+		exit(1)
+	}
+
+	fmt.Printf("Hash lookups (%s): %d hits, value sum %d\n", *variant, hits, valSum)
+	reportKernel("hashtable", *variant, elapsed, valSum)
+}
@@ -0,0 +1,373 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+type counts struct {
+	objects, arrays, keys, strings, numbers, literals int64
+}
+
+// Scanner states. Containers are tracked on an explicit stack,
+// so nesting depth never turns into recursion.
+const (
+	stValue      = iota // a value is required
+	stValueOrEnd        // just after '[': a value or ']'
+	stKeyOrEnd          // just after '{': a key or '}'
+	stKey               // after ',' inside an object
+	stColon             // after a key
+	stAfter             // after a value: ',', a closer, or the end
+)
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isHex(c byte) bool {
+	return isDigit(c) || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+// buf[i] is the opening quote. Returns the index past the closing quote, or -1.
+func scanString(buf []byte, i int) int {
+	i++
+	for i < len(buf) {
+		c := buf[i]
+		switch {
+		case c == '"':
+			return i + 1
+		case c == '\\':
+			if i+1 >= len(buf) {
+				return -1
+			}
+			switch buf[i+1] {
+			case '"', '\\', '/', 'b', 'f', 'n', 'r', 't':
+				i += 2
+			case 'u':
+				if i+6 > len(buf) {
+					return -1
+				}
+				for _, h := range buf[i+2 : i+6] {
+					if !isHex(h) {
+						return -1
+					}
+				}
+				i += 6
+			default:
+				return -1
+			}
+		case c < 0x20:
+			return -1
+		default:
+			i++
+		}
+	}
+	return -1
+}
+
+// -?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][+-]?[0-9]+)?
+// Returns the index past the number, or -1.
+func scanNumber(buf []byte, i int) int {
+	if buf[i] == '-' {
+		i++
+	}
+	if i >= len(buf) || !isDigit(buf[i]) {
+		return -1
+	}
+	if buf[i] == '0' {
+		i++
+	} else {
+		for i < len(buf) && isDigit(buf[i]) {
+			i++
+		}
+	}
+	if i < len(buf) && buf[i] == '.' {
+		i++
+		if i >= len(buf) || !isDigit(buf[i]) {
+			return -1
+		}
+		for i < len(buf) && isDigit(buf[i]) {
+			i++
+		}
+	}
+	if i < len(buf) && (buf[i] == 'e' || buf[i] == 'E') {
+		i++
+		if i < len(buf) && (buf[i] == '+' || buf[i] == '-') {
+			i++
+		}
+		if i >= len(buf) || !isDigit(buf[i]) {
+			return -1
+		}
+		for i < len(buf) && isDigit(buf[i]) {
+			i++
+		}
+	}
+	return i
+}
+
+func scanLiteral(buf []byte, i int) int {
+	for _, lit := range []string{"true", "false", "null"} {
+		if bytes.HasPrefix(buf[i:], []byte(lit)) {
+			return i + len(lit)
+		}
+	}
+	return -1
+}
+
+// Validates a single JSON document and counts what it contains.
+func scan(buf []byte) (counts, bool) {
+	var c counts
+	stack := make([]byte, 0, 64)
+	state := stValue
+	i := 0
+	for {
+		for i < len(buf) && isSpace(buf[i]) {
+			i++
+		}
+		if i == len(buf) {
+			return c, state == stAfter && len(stack) == 0
+		}
+		ch := buf[i]
+
+		switch state {
+		case stValueOrEnd:
+			if ch == ']' {
+				stack = stack[:len(stack)-1]
+				state = stAfter
+				i++
+				continue
+			}
+			fallthrough
+		case stValue:
+			switch {
+			case ch == '{':
+				stack = append(stack, '{')
+				c.objects++
+				state = stKeyOrEnd
+				i++
+			case ch == '[':
+				stack = append(stack, '[')
+				c.arrays++
+				state = stValueOrEnd
+				i++
+			case ch == '"':
+				i = scanString(buf, i)
+				c.strings++
+				state = stAfter
+			case ch == '-' || isDigit(ch):
+				i = scanNumber(buf, i)
+				c.numbers++
+				state = stAfter
+			default:
+				i = scanLiteral(buf, i)
+				c.literals++
+				state = stAfter
+			}
+		case stKeyOrEnd:
+			if ch == '}' {
+				stack = stack[:len(stack)-1]
+				state = stAfter
+				i++
+				continue
+			}
+			fallthrough
+		case stKey:
+			if ch != '"' {
+				return c, false
+			}
+			i = scanString(buf, i)
+			c.keys++
+			state = stColon
+		case stColon:
+			if ch != ':' {
+				return c, false
+			}
+			state = stValue
+			i++
+		case stAfter:
+			if len(stack) == 0 {
+				// Trailing garbage after the document
+				return c, false
+			}
+			top := stack[len(stack)-1]
+			switch {
+			case ch == ',' && top == '{':
+				state = stKey
+			case ch == ',' && top == '[':
+				state = stValue
+			case ch == '}' && top == '{', ch == ']' && top == '[':
+				stack = stack[:len(stack)-1]
+			default:
+				return c, false
+			}
+			i++
+		}
+
+		if i < 0 {
+			return c, false
+		}
+	}
+}
+
+// Builds a top-level array of records until the document reaches size
+// bytes, tallying everything it emits so the scanner can be checked.
+func generate(size int, seed int64) ([]byte, counts) {
+	r := newLCG(seed)
+	var c counts
+	var b bytes.Buffer
+	b.Grow(size + 1024)
+
+	words := []string{"alpha", "beta", "gamma", "delta", "tab\\tbed", "quote\\\"d", "uni\\u00e9", "path\\/to", "line\\nbreak"}
+	str := func() {
+		b.WriteByte('"')
+		b.WriteString(words[r.below(int64(len(words)))])
+		b.WriteString(strconv.FormatInt(r.below(1000), 10))
+		b.WriteByte('"')
+		c.strings++
+	}
+	key := func(k string) {
+		b.WriteByte('"')
+		b.WriteString(k)
+		b.WriteString(`": `)
+		c.keys++
+	}
+	num := func() {
+		switch r.below(3) {
+		case 0:
+			b.WriteString(strconv.FormatInt(r.below(2_000_000)-1_000_000, 10))
+		case 1:
+			b.WriteString(strconv.FormatFloat(r.float()*1000-500, 'f', 4, 64))
+		default:
+			b.WriteString(strconv.FormatFloat(r.float()*1e-3, 'e', 6, 64))
+		}
+		c.numbers++
+	}
+
+	b.WriteString("[\n")
+	c.arrays++
+	for id := int64(0); b.Len() < size; id++ {
+		if id > 0 {
+			b.WriteString(",\n")
+		}
+		b.WriteString("  {")
+		c.objects++
+
+		key("id")
+		b.WriteString(strconv.FormatInt(id, 10))
+		c.numbers++
+		b.WriteString(", ")
+		key("name")
+		str()
+		b.WriteString(", ")
+		key("score")
+		num()
+		b.WriteString(", ")
+
+		key("tags")
+		b.WriteByte('[')
+		c.arrays++
+		tags := r.below(4)
+		for t := int64(0); t < tags; t++ {
+			if t > 0 {
+				b.WriteString(", ")
+			}
+			str()
+		}
+		b.WriteString("], ")
+
+		key("matrix")
+		b.WriteByte('[')
+		c.arrays++
+		for row := 0; row < 2; row++ {
+			if row > 0 {
+				b.WriteString(", ")
+			}
+			b.WriteByte('[')
+			c.arrays++
+			for col := 0; col < 3; col++ {
+				if col > 0 {
+					b.WriteString(", ")
+				}
+				num()
+			}
+			b.WriteByte(']')
+		}
+		b.WriteString("], ")
+
+		key("meta")
+		b.WriteByte('{')
+		c.objects++
+		key("active")
+		if r.below(2) == 0 {
+			b.WriteString("true")
+		} else {
+			b.WriteString("false")
+		}
+		c.literals++
+		b.WriteString(", ")
+		key("parent")
+		b.WriteString("null")
+		c.literals++
+		b.WriteString("}}")
+	}
+	b.WriteString("\n]\n")
+	return b.Bytes(), c
+}
+
+// Documents the scanner must reject.
+var invalid = []string{
+	``, `{`, `[1,]`, `{"a" 1}`, `{"a":}`, `[01]`, `[1.]`, `[1e]`, `["\x"]`,
+	`["\u12"]`, `[tru]`, `{"a":1,}`, `[1] 2`, `{1:2}`, `["a` + "\n" + `"]`, `[}`, `{]`,
+}
+
+var presets = sizePresets{
+	"small":  {"mb": "1"},
+	"medium": {"mb": "8"},
+	"large":  {"mb": "64"},
+	"huge":   {"mb": "256"},
+}
+
+func main() {
+	size := flag.Int("mb", 8, "document size in MiB")
+	reps := flag.Int("reps", 10, "scans of the document")
+	seed := flag.Int64("seed", 42, "LCG seed for the generated document")
+	parseWithSize(presets)
+	defer startProfiling()()
+
+	for _, doc := range invalid {
+		if _, ok := scan([]byte(doc)); ok {
+			fmt.Fprintf(os.Stderr, "scanner accepted invalid document %q\n", doc)
+			exit(1)
+		}
+	}
+
+	doc, want := generate(*size<<20, *seed)
+
+	var got counts
+	start := startKernel()
+	for rep := 0; rep < *reps; rep++ {
+		var ok bool
+		got, ok = scan(doc)
+		if !ok {
+			fmt.Fprintln(os.Stderr, "scanner rejected the generated document")
+			exit(1)
+		}
+	}
+	elapsed := time.Since(start)
+	if *reps > 0 && got != want {
+		fmt.Fprintf(os.Stderr, "counts %+v, generator emitted %+v\n", got, want)
+		exit(1)
+	}
+
+	fmt.Printf("JSON scan: %d objects, %d arrays, %d strings, %d numbers\n",
+		want.objects, want.arrays, want.strings, want.numbers)
+	reportKernel("json-scan", defaultVariant, elapsed, want.objects+want.arrays+want.strings+want.numbers)
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"math"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Reads one signed integer per line from stdin (or -file) and reports
+// count, sum, min and max. -gen writes a matching input instead, so the
+// Makefile can produce the file once and feed it to every run.
+
+type stats struct {
+	count, sum, min, max int64
+}
+
+func newStats() stats {
+	return stats{min: math.MaxInt64, max: math.MinInt64}
+}
+
+func (s *stats) add(v int64) {
+	s.count++
+	s.sum += v
+	s.min = min(s.min, v)
+	s.max = max(s.max, v)
+}
+
+// Parses lines byte by byte straight out of a read buffer, carrying the
+// partial number across chunk boundaries.
+func readHand(in io.Reader) (stats, error) {
+	st := newStats()
+	buf := make([]byte, 64<<10)
+	var v int64 = 0
+	neg, digits := false, 0
+	for {
+		n, err := in.Read(buf)
+		for _, c := range buf[:n] {
+			switch {
+			case c >= '0' && c <= '9':
+				v = v*10 + int64(c-'0')
+				digits++
+			case c == '-' && digits == 0 && !neg:
+				neg = true
+			case c == '\n':
+				if digits == 0 {
+					return st, fmt.Errorf("line %d: no digits", st.count+1)
+				}
+				st.add(sign(v, neg))
+				v, neg, digits = 0, false, 0
+			case c == '\r':
+			default:
+				return st, fmt.Errorf("line %d: unexpected byte %q", st.count+1, c)
+			}
+		}
+		if err == io.EOF {
+			// Last line without a trailing newline
+			if digits > 0 {
+				st.add(sign(v, neg))
+			}
+			return st, nil
+		}
+		if err != nil {
+			return st, err
+		}
+	}
+}
+
+func sign(v int64, neg bool) int64 {
+	if neg {
+		return -v
+	}
+	return v
+}
+
+// The idiomatic version: bufio.Scanner plus strconv.
+func readScanner(in io.Reader) (stats, error) {
+	st := newStats()
+	sc := bufio.NewScanner(in)
+	sc.Buffer(make([]byte, 64<<10), 64<<10)
+	for sc.Scan() {
+		v, err := strconv.ParseInt(string(bytes.TrimSuffix(sc.Bytes(), []byte{'\r'})), 10, 64)
+		if err != nil {
+			return st, fmt.Errorf("line %d: %v", st.count+1, err)
+		}
+		st.add(v)
+	}
+	return st, sc.Err()
+}
+
+func generate(out io.Writer, lines int64, seed int64) error {
+	w := bufio.NewWriterSize(out, 64<<10)
+	r := newLCG(seed)
+	buf := make([]byte, 0, 24)
+	for i := int64(0); i < lines; i++ {
+		buf = strconv.AppendInt(buf[:0], r.below(1<<30)-1<<29, 10)
+		buf = append(buf, '\n')
+		if _, err := w.Write(buf); err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
+
+// -size reads the files bench geninput writes (bench run makes them as
+// it needs them); the line counts are in cmd/bench/geninput.go.
+var presets = sizePresets{
+	"small":  {"file": "build/inputs/lines-small.txt"},
+	"medium": {"file": "build/inputs/lines-medium.txt"},
+	"large":  {"file": "build/inputs/lines-large.txt"},
+	"huge":   {"file": "build/inputs/lines-huge.txt"},
+}
+
+func main() {
+	file := flag.String("file", "", "read this file instead of stdin")
+	gen := flag.Int64("gen", 0, "write this many generated lines to stdout and exit")
+	seed := flag.Int64("seed", 42, "LCG seed for -gen")
+	variant := flag.String("variant", "hand", "hand or scanner")
+	parseWithSize(presets)
+	defer startProfiling()()
+
+	if *gen > 0 {
+		if err := generate(os.Stdout, *gen, *seed); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			exit(1)
+		}
+		return
+	}
+
+	var read func(io.Reader) (stats, error)
+	switch *variant {
+	case "hand":
+		read = readHand
+	case "scanner":
+		read = readScanner
+	default:
+		fmt.Fprintf(os.Stderr, "unknown variant %q\n", *variant)
+		exit(2)
+	}
+
+	in := io.Reader(os.Stdin)
+	if *file != "" {
+		f, err := os.Open(*file)
+		if size := flag.Lookup("size").Value.String(); size != "" && errors.Is(err, fs.ErrNotExist) {
+			fmt.Fprintf(os.Stderr, "%v; bench geninput -size %s lines writes it\n", err, size)
+			exit(2)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			exit(2)
+		}
+		defer f.Close()
+		in = f
+	}
+
+	start := startKernel()
+	st, err := read(in)
+	elapsed := time.Since(start)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", *variant, err)
+		exit(1)
+	}
+	if st.count == 0 {
+		fmt.Fprintln(os.Stderr, "no input lines")
+		exit(1)
+	}
+
+	fmt.Printf("Lines: %d, sum: %d, min: %d, max: %d\n", st.count, st.sum, st.min, st.max)
+	reportKernel("lines", *variant, elapsed, st.sum)
+}
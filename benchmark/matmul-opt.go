@@ -1,6 +1,10 @@
 package main
 
-import "fmt"
+import (
+	"flag"
+	"fmt"
+	"time"
+)
 
 func fillMatrix(arr []int64, n int64, seed int64) {
 	currentSeed := seed
@@ -35,8 +39,26 @@ func trace(arr []int64, n int64) int64 {
 	return acc
 }
 
+var presets = sizePresets{
+	"small":  {"n": "200"},
+	"medium": {"n": "500"},
+	"large":  {"n": "1000"},
+	"huge":   {"n": "2000"},
+}
+
 func main() {
-	var n int64 = 500
+	parityFlag("mat-mul-opt.mml")
+	size := flag.Int64("n", 500, "matrix dimension")
+	verify := flag.Bool("verify", false, "check the result against expected.go")
+	checks := flag.Int("check", 0, "run this many randomized checks against a naive multiply instead of the benchmark")
+	parseWithSize(presets)
+	defer startProfiling()()
+
+	if *checks > 0 {
+		runChecks("matmul-opt", *checks, checkMatMul(matMul))
+	}
+
+	n := *size
 	A := make([]int64, n*n)
 	B := make([]int64, n*n)
 	C := make([]int64, n*n)
@@ -44,8 +66,14 @@ func main() {
 	fillMatrix(A, n, 42)
 	fillMatrix(B, n, 1337)
 
+	start := startKernel()
 	matMul(A, B, C, n)
+	elapsed := time.Since(start)
 
 	result := trace(C, n)
 	fmt.Printf("Trace Checksum: %d\n", result)
+	reportKernel("matmul", "opt", elapsed, result)
+	if *verify {
+		verifyResult("matmul", fmt.Sprintf("n=%d seeds=42,1337", n), result)
+	}
 }
@@ -1,7 +1,10 @@
 package main
 
 import (
+	"flag"
 	"fmt"
+	"os"
+	"time"
 )
 
 func fillMatrix(arr []int64, n int64, seed int64) {
@@ -28,6 +31,83 @@ func matMul(A []int64, B []int64, C []int64, n int64) {
 	}
 }
 
+// Alternative inner loops for micro-optimization experiments, picked with
+// -kernel; -verify and -check test whichever is picked. Products wrap
+// the same way in any order, so they all give the same trace.
+var matMulKernels = map[string]func(A, B, C []int64, n int64){
+	"default": matMul,
+	"unroll":  matMulUnroll,
+	"stride2": matMulStride2,
+	"split":   matMulSplit,
+}
+
+var kernel = matMul
+
+// The k loop four steps a trip into one accumulator.
+func matMulUnroll(A []int64, B []int64, C []int64, n int64) {
+	for i := int64(0); i < n; i++ {
+		for j := int64(0); j < n; j++ {
+			var acc int64 = 0
+			k := int64(0)
+			for ; k+3 < n; k += 4 {
+				acc += A[(i*n)+k]*B[(k*n)+j] +
+					A[(i*n)+k+1]*B[((k+1)*n)+j] +
+					A[(i*n)+k+2]*B[((k+2)*n)+j] +
+					A[(i*n)+k+3]*B[((k+3)*n)+j]
+			}
+			for ; k < n; k++ {
+				acc += A[(i*n)+k] * B[(k*n)+j]
+			}
+			C[(i*n)+j] = acc
+		}
+	}
+}
+
+// Two output columns a pass, so every A element loaded feeds two sums.
+func matMulStride2(A []int64, B []int64, C []int64, n int64) {
+	for i := int64(0); i < n; i++ {
+		j := int64(0)
+		for ; j+1 < n; j += 2 {
+			var acc0, acc1 int64
+			for k := int64(0); k < n; k++ {
+				valA := A[(i*n)+k]
+				acc0 += valA * B[(k*n)+j]
+				acc1 += valA * B[(k*n)+j+1]
+			}
+			C[(i*n)+j] = acc0
+			C[(i*n)+j+1] = acc1
+		}
+		if j < n {
+			var acc int64 = 0
+			for k := int64(0); k < n; k++ {
+				acc += A[(i*n)+k] * B[(k*n)+j]
+			}
+			C[(i*n)+j] = acc
+		}
+	}
+}
+
+// Four accumulators over interleaved k, summed at the end, to break the
+// add dependency chain.
+func matMulSplit(A []int64, B []int64, C []int64, n int64) {
+	for i := int64(0); i < n; i++ {
+		for j := int64(0); j < n; j++ {
+			var acc0, acc1, acc2, acc3 int64
+			k := int64(0)
+			for ; k+3 < n; k += 4 {
+				acc0 += A[(i*n)+k] * B[(k*n)+j]
+				acc1 += A[(i*n)+k+1] * B[((k+1)*n)+j]
+				acc2 += A[(i*n)+k+2] * B[((k+2)*n)+j]
+				acc3 += A[(i*n)+k+3] * B[((k+3)*n)+j]
+			}
+			for ; k < n; k++ {
+				acc0 += A[(i*n)+k] * B[(k*n)+j]
+			}
+			C[(i*n)+j] = acc0 + acc1 + acc2 + acc3
+		}
+	}
+}
+
 func trace(arr []int64, n int64) int64 {
 	var acc int64 = 0
 	for i := int64(0); i < n; i++ {
@@ -36,8 +116,34 @@ func trace(arr []int64, n int64) int64 {
 	return acc
 }
 
+var presets = sizePresets{
+	"small":  {"n": "200"},
+	"medium": {"n": "500"},
+	"large":  {"n": "1000"},
+	"huge":   {"n": "2000"},
+}
+
 func main() {
-	var n int64 = 500
+	parityFlag("mat-mul.mml")
+	size := flag.Int64("n", 500, "matrix dimension")
+	verify := flag.Bool("verify", false, "check the result against expected.go")
+	checks := flag.Int("check", 0, "run this many randomized checks against a naive multiply instead of the benchmark")
+	kernelName := flag.String("kernel", "default", "inner loops: default, unroll, stride2 or split")
+	parseWithSize(presets)
+	defer startProfiling()()
+
+	k, ok := matMulKernels[*kernelName]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown kernel %q\n", *kernelName)
+		exit(2)
+	}
+	kernel = k
+
+	if *checks > 0 {
+		runChecks("matmul -kernel "+*kernelName, *checks, checkMatMul(kernel))
+	}
+
+	n := *size
 	A := make([]int64, n*n)
 	B := make([]int64, n*n)
 	C := make([]int64, n*n)
@@ -45,8 +151,14 @@ func main() {
 	fillMatrix(A, n, 42)
 	fillMatrix(B, n, 1337)
 
-	matMul(A, B, C, n)
+	start := startKernel()
+	kernel(A, B, C, n)
+	elapsed := time.Since(start)
 
 	result := trace(C, n)
 	fmt.Printf("Trace Checksum: %d\n", result)
+	reportKernel("matmul", *kernelName, elapsed, result)
+	if *verify {
+		verifyResult("matmul", fmt.Sprintf("n=%d seeds=42,1337", n), result)
+	}
 }
@@ -0,0 +1,8 @@
+package main
+
+import "maps"
+
+// matmul.go's -kernel alternatives, for the tests in matmul_test.go.
+func init() {
+	maps.Copy(matMuls, matMulKernels)
+}
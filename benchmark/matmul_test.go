@@ -0,0 +1,106 @@
+package main
+
+import (
+	"maps"
+	"slices"
+	"testing"
+)
+
+// Builds against any of the matmuls, e.g.
+//
+//	go test matmul-opt.go matmul_test.go expected_test.go $(GO_SHARED)
+//
+// matmul.go's -kernel alternatives are added from matmul_kernels_test.go,
+// which only builds with it; make test picks it up.
+
+// The multiplies under test, by kernel name.
+var matMuls = map[string]func(A, B, C []int64, n int64){"default": matMul}
+
+// Runs f as a subtest for each multiply.
+func forEachMatMul(t *testing.T, f func(t *testing.T, mul func(A, B, C []int64, n int64))) {
+	for _, name := range slices.Sorted(maps.Keys(matMuls)) {
+		t.Run(name, func(t *testing.T) { f(t, matMuls[name]) })
+	}
+}
+
+func TestMatMulChecks(t *testing.T) {
+	forEachMatMul(t, func(t *testing.T, mul func(A, B, C []int64, n int64)) {
+		check := checkMatMul(mul)
+		for i := 0; i < 200; i++ {
+			if err := check(i, newLCG(streamSeed(checkSeed, i))); err != nil {
+				t.Fatalf("case %d: %v", i, err)
+			}
+		}
+	})
+}
+
+// The matrices the benchmark multiplies are the ones the checks use.
+func TestFillMatrix(t *testing.T) {
+	for _, n := range []int64{1, 7, 64} {
+		got := make([]int64, n*n)
+		fillMatrix(got, n, 1337)
+		want := randomMatrix(n, 1337)
+		for k := range got {
+			if got[k] != want[k] {
+				t.Fatalf("n=%d: element %d is %d, want %d", n, k, got[k], want[k])
+			}
+		}
+	}
+}
+
+// A×I = I×A = A, and trace(AB) = trace(BA).
+func TestMatMulIdentities(t *testing.T) {
+	forEachMatMul(t, func(t *testing.T, mul func(A, B, C []int64, n int64)) {
+		for _, n := range []int64{1, 2, 3, 5, 8, 17, 33, 64} {
+			A, B := randomMatrix(n, 42), randomMatrix(n, 1337)
+			I := make([]int64, n*n)
+			for i := int64(0); i < n; i++ {
+				I[i*n+i] = 1
+			}
+			for _, m := range [][2][]int64{{A, I}, {I, A}} {
+				C := make([]int64, n*n)
+				mul(m[0], m[1], C, n)
+				for k := range C {
+					if C[k] != A[k] {
+						t.Fatalf("n=%d: multiplying by the identity changed element %d", n, k)
+					}
+				}
+			}
+			AB, BA := make([]int64, n*n), make([]int64, n*n)
+			mul(A, B, AB, n)
+			mul(B, A, BA, n)
+			if trace(AB, n) != trace(BA, n) {
+				t.Fatalf("n=%d: trace(AB) = %d, trace(BA) = %d", n, trace(AB, n), trace(BA, n))
+			}
+		}
+	})
+}
+
+func FuzzMatMul(f *testing.F) {
+	f.Add(int64(1), int64(42), int64(1337))
+	f.Add(int64(4), int64(0), int64(-1))
+	f.Add(int64(13), int64(7), int64(7))
+	f.Fuzz(func(t *testing.T, n, seedA, seedB int64) {
+		if n < 1 || n > 64 {
+			t.Skip()
+		}
+		for name, mul := range matMuls {
+			if err := checkMatMulSeeds(mul, n, seedA, seedB); err != nil {
+				t.Errorf("%s: %v", name, err)
+			}
+		}
+	})
+}
+
+func TestMatMulExpected(t *testing.T) {
+	forEachMatMul(t, func(t *testing.T, mul func(A, B, C []int64, n int64)) {
+		testExpected(t, "matmul", "n", 500, func(t *testing.T, c expectedCase) int64 {
+			n, seeds := c.int(t, "n"), c.ints(t, "seeds")
+			A, B, C := make([]int64, n*n), make([]int64, n*n), make([]int64, n*n)
+			fillMatrix(A, n, seeds[0])
+			fillMatrix(B, n, seeds[1])
+			mul(A, B, C, n)
+			return trace(C, n)
+		})
+	})
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	rtrace "runtime/trace"
+	"time"
+)
+
+// Every benchmark also prints one line for tools, e.g.
+//
+//	MMLBENCH name=sieve variant=default ns=1834211 checksum=78498
+//
+// -mem adds stack_peak and heap_peak in bytes (see profile.go), and a
+// benchmark can add key=value fields of its own, like pointer-chase's
+// ns_per_hop.
+//
+// ns covers the kernel only, timed on the monotonic clock, so process
+// startup, input generation and verification are left out; external
+// timers can't do that and it distorts small sizes. The line goes to
+// stderr so stdout stays the human output that -parity diffs.
+
+// Variant name for benchmarks that only have one.
+const defaultVariant = "default"
+
+// The trace region of the kernel being timed, if -trace is on.
+var kernelRegion *rtrace.Region
+
+// startKernel returns the start time of a timed region. Use it instead
+// of time.Now so -trace can mark the region; reportKernel closes it.
+func startKernel() time.Time {
+	startTrace()
+	startMemWatch()
+	if tracing != nil && kernelRegion == nil {
+		kernelRegion = rtrace.StartRegion(context.Background(), "kernel")
+	}
+	return time.Now()
+}
+
+func reportKernel(name, variant string, elapsed time.Duration, checksum any, fields ...string) {
+	if kernelRegion != nil {
+		kernelRegion.End()
+		kernelRegion = nil
+		rtrace.Logf(context.Background(), "mmlbench", "%s/%s %v", name, variant, elapsed)
+	}
+	extra := ""
+	for _, f := range fields {
+		extra += " " + f
+	}
+	fmt.Fprintf(os.Stderr, "MMLBENCH name=%s variant=%s ns=%d checksum=%v%s%s\n",
+		name, variant, elapsed.Nanoseconds(), checksum, extra, stopMemWatch())
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// Samples are split into a fixed number of chunks, each with its own
+// LCG stream. Both variants walk the same chunks and add up their counts
+// in chunk order, so the hit count (and pi) depends only on the seed,
+// never on GOMAXPROCS or the number of workers; -verify holds for both.
+const chunks = 64
+
+// Counts points of the unit square that fall inside the quarter circle.
+func countHits(samples int64, seed int64) int64 {
+	r := newLCG(seed)
+	var hits int64 = 0
+	for i := int64(0); i < samples; i++ {
+		x := r.float()
+		y := r.float()
+		if x*x+y*y < 1 {
+			hits++
+		}
+	}
+	return hits
+}
+
+// Chunk c gets samples/chunks points, the first samples%chunks get one more.
+func chunkSize(samples int64, c int) int64 {
+	size := samples / chunks
+	if int64(c) < samples%chunks {
+		size++
+	}
+	return size
+}
+
+func serial(samples, seed int64) int64 {
+	var hits int64 = 0
+	for c := 0; c < chunks; c++ {
+		hits += countHits(chunkSize(samples, c), streamSeed(seed, c))
+	}
+	return hits
+}
+
+// Workers pull chunk indices from a channel and write into a per-chunk
+// slot, which is summed in order once they're all done. Which worker got
+// which chunk never shows in the result.
+func parallel(samples, seed int64, workers int) int64 {
+	counts := make([]int64, chunks)
+	work := make(chan int, chunks)
+	for c := 0; c < chunks; c++ {
+		work <- c
+	}
+	close(work)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for c := range work {
+				counts[c] = countHits(chunkSize(samples, c), streamSeed(seed, c))
+			}
+		}()
+	}
+	wg.Wait()
+
+	var hits int64 = 0
+	for _, h := range counts {
+		hits += h
+	}
+	return hits
+}
+
+var presets = sizePresets{
+	"small":  {"n": "20000000"},
+	"medium": {"n": "200000000"},
+	"large":  {"n": "1000000000"},
+	"huge":   {"n": "4000000000"},
+}
+
+func main() {
+	samples := flag.Int64("n", 200_000_000, "number of samples")
+	seed := flag.Int64("seed", 42, "LCG seed")
+	variant := flag.String("variant", "serial", "serial or parallel")
+	workers := flag.Int("workers", runtime.GOMAXPROCS(0), "goroutines for the parallel variant")
+	verify := flag.Bool("verify", false, "check the hit count against expected.go")
+	parseWithSize(presets)
+	defer startProfiling()()
+
+	if *samples < 1 {
+		fmt.Fprintln(os.Stderr, "-n must be at least 1")
+		exit(2)
+	}
+
+	var hits int64
+	start := startKernel()
+	switch *variant {
+	case "serial":
+		hits = serial(*samples, *seed)
+	case "parallel":
+		hits = parallel(*samples, *seed, max(*workers, 1))
+	default:
+		fmt.Fprintf(os.Stderr, "unknown variant %q\n", *variant)
+		exit(2)
+	}
+	elapsed := time.Since(start)
+
+	pi := 4 * float64(hits) / float64(*samples)
+	fmt.Printf("Pi estimate (%s): %.10f, hits: %d\n", *variant, pi, hits)
+	if *verify {
+		verifyResult("montecarlo-pi", fmt.Sprintf("n=%d seed=%d", *samples, *seed), hits)
+	}
+	reportKernel("montecarlo-pi", *variant, elapsed, hits)
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"testing"
+)
+
+func TestMonteCarloPiExpected(t *testing.T) {
+	testExpected(t, "montecarlo-pi", "n", 20_000_000, func(t *testing.T, c expectedCase) int64 {
+		return serial(c.int(t, "n"), c.int(t, "seed"))
+	})
+}
+
+// The parallel hits are the serial ones at every GOMAXPROCS and worker
+// count, more workers than chunks included. Sample counts that don't
+// divide evenly into chunks make the last ones uneven.
+func TestMonteCarloPiParallel(t *testing.T) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(0))
+	for _, samples := range []int64{0, 1, chunks - 1, 100_003, 2_000_000} {
+		want := serial(samples, 42)
+		for _, procs := range []int{1, 2, 4, 8} {
+			runtime.GOMAXPROCS(procs)
+			for _, workers := range []int{1, 2, 3, 7, procs * 3, chunks + 1} {
+				t.Run(fmt.Sprintf("n=%d/procs=%d/workers=%d", samples, procs, workers), func(t *testing.T) {
+					if got := parallel(samples, 42, workers); got != want {
+						t.Errorf("got %d hits, serial has %d", got, want)
+					}
+				})
+			}
+		}
+	}
+}
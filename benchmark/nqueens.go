@@ -1,7 +1,9 @@
 package main
 
 import (
+	"flag"
 	"fmt"
+	"time"
 )
 
 func absInt(x int64) int64 {
@@ -56,10 +58,47 @@ func solveRow(board []int64, row, n int64) int64 {
 	return solveCol(board, row, n, 0)
 }
 
+// Solution counts for n = 1, 2, ... (OEIS A000170), the reference for
+// -check. Stops at 10 so a thousand cases stay quick.
+var nqueensOEIS = []int64{1, 0, 0, 2, 10, 4, 40, 92, 352, 724}
+
+// Nothing random here: case i is board size i%10 + 1.
+func checkNQueens(i int, _ *lcg) error {
+	n := int64(i%len(nqueensOEIS)) + 1
+	if got, want := solveRow(make([]int64, n), 0, n), nqueensOEIS[n-1]; got != want {
+		return fmt.Errorf("n=%d: got %d solutions, want %d", n, got, want)
+	}
+	return nil
+}
+
+var presets = sizePresets{
+	"small":  {"n": "10"},
+	"medium": {"n": "12"},
+	"large":  {"n": "14"},
+	"huge":   {"n": "15"},
+}
+
 func main() {
-	n := int64(12)
+	parityFlag("nqueens.mml")
+	size := flag.Int64("n", 12, "board size")
+	verify := flag.Bool("verify", false, "check the result against expected.go")
+	checks := flag.Int("check", 0, "run this many checks against the OEIS counts instead of the benchmark")
+	parseWithSize(presets)
+	defer startProfiling()()
+
+	if *checks > 0 {
+		runChecks("nqueens", *checks, checkNQueens)
+	}
+
+	n := *size
 	board := make([]int64, n)
 
+	start := startKernel()
 	solutions := solveRow(board, 0, n)
+	elapsed := time.Since(start)
 	fmt.Printf("Solutions for %d-queens: %d\n", n, solutions)
+	reportKernel("nqueens", defaultVariant, elapsed, solutions)
+	if *verify {
+		verifyResult("nqueens", fmt.Sprintf("n=%d", n), solutions)
+	}
 }
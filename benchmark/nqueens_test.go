@@ -0,0 +1,10 @@
+package main
+
+import "testing"
+
+func TestNQueensExpected(t *testing.T) {
+	testExpected(t, "nqueens", "n", 11, func(t *testing.T, c expectedCase) int64 {
+		n := c.int(t, "n")
+		return solveRow(make([]int64, n), 0, n)
+	})
+}
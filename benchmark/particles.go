@@ -0,0 +1,184 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"time"
+)
+
+const (
+	dt      = 0.01
+	gravity = -9.81
+	damping = 0.9
+)
+
+// Array of structs: one 64-byte particle per cache line.
+// The update only touches position and velocity, so mass and id
+// are dead weight the AoS layout drags through the cache anyway.
+type particle struct {
+	x, y, z    float64
+	vx, vy, vz float64
+	mass       float64
+	id         int64
+}
+
+// Struct of arrays: each field is its own contiguous stream.
+type particles struct {
+	x, y, z    []float64
+	vx, vy, vz []float64
+	mass       []float64
+	id         []int64
+}
+
+func newParticles(n int) *particles {
+	return &particles{
+		x: make([]float64, n), y: make([]float64, n), z: make([]float64, n),
+		vx: make([]float64, n), vy: make([]float64, n), vz: make([]float64, n),
+		mass: make([]float64, n), id: make([]int64, n),
+	}
+}
+
+// Both layouts start from the same state, drawn in particle order.
+func initial(n int, seed int64) []particle {
+	r := newLCG(seed)
+	ps := make([]particle, n)
+	for i := range ps {
+		ps[i] = particle{
+			x: r.float() * 100, y: r.float() * 100, z: r.float() * 100,
+			vx: r.float()*2 - 1, vy: r.float()*2 - 1, vz: r.float()*2 - 1,
+			mass: 1 + r.float(), id: int64(i),
+		}
+	}
+	return ps
+}
+
+// One integration step for a single particle: gravity on y,
+// explicit Euler, and an inelastic bounce off the y = 0 floor.
+// Both layouts inline the same arithmetic so results are bit-identical.
+func stepAoS(ps []particle) {
+	for i := range ps {
+		p := &ps[i]
+		p.vy += gravity * dt
+		p.x += p.vx * dt
+		p.y += p.vy * dt
+		p.z += p.vz * dt
+		if p.y < 0 {
+			p.y = -p.y
+			p.vy = -p.vy * damping
+		}
+	}
+}
+
+func stepSoA(ps *particles) {
+	// Reslicing to a common length lets the compiler drop bounds checks
+	x := ps.x
+	y, z := ps.y[:len(x)], ps.z[:len(x)]
+	vx, vy, vz := ps.vx[:len(x)], ps.vy[:len(x)], ps.vz[:len(x)]
+	for i := range x {
+		vy[i] += gravity * dt
+		x[i] += vx[i] * dt
+		y[i] += vy[i] * dt
+		z[i] += vz[i] * dt
+		if y[i] < 0 {
+			y[i] = -y[i]
+			vy[i] = -vy[i] * damping
+		}
+	}
+}
+
+// Straightforward per-particle reference, steps innermost.
+func reference(p particle, steps int) particle {
+	for s := 0; s < steps; s++ {
+		p.vy += gravity * dt
+		p.x += p.vx * dt
+		p.y += p.vy * dt
+		p.z += p.vz * dt
+		if p.y < 0 {
+			p.y = -p.y
+			p.vy = -p.vy * damping
+		}
+	}
+	return p
+}
+
+var presets = sizePresets{
+	"small":  {"n": "200000"},
+	"medium": {"n": "2000000"},
+	"large":  {"n": "8000000"},
+	"huge":   {"n": "32000000"},
+}
+
+func main() {
+	n := flag.Int("n", 2_000_000, "number of particles")
+	steps := flag.Int("steps", 50, "integration steps")
+	seed := flag.Int64("seed", 42, "LCG seed for the initial state")
+	variant := flag.String("variant", "aos", "aos or soa")
+	parseWithSize(presets)
+	defer startProfiling()()
+
+	if *n < 1 {
+		fmt.Fprintln(os.Stderr, "-n must be at least 1")
+		exit(2)
+	}
+
+	start := initial(*n, *seed)
+
+	// Final state, gathered back into AoS form for checking. Copying in
+	// and out of either layout stays outside the timed region.
+	final := make([]particle, *n)
+	var elapsed time.Duration
+	switch *variant {
+	case "aos":
+		copy(final, start)
+		begin := startKernel()
+		for s := 0; s < *steps; s++ {
+			stepAoS(final)
+		}
+		elapsed = time.Since(begin)
+	case "soa":
+		ps := newParticles(*n)
+		for i, p := range start {
+			ps.x[i], ps.y[i], ps.z[i] = p.x, p.y, p.z
+			ps.vx[i], ps.vy[i], ps.vz[i] = p.vx, p.vy, p.vz
+			ps.mass[i], ps.id[i] = p.mass, p.id
+		}
+		begin := startKernel()
+		for s := 0; s < *steps; s++ {
+			stepSoA(ps)
+		}
+		elapsed = time.Since(begin)
+		for i := range final {
+			final[i] = particle{
+				x: ps.x[i], y: ps.y[i], z: ps.z[i],
+				vx: ps.vx[i], vy: ps.vy[i], vz: ps.vz[i],
+				mass: ps.mass[i], id: ps.id[i],
+			}
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "unknown variant %q\n", *variant)
+		exit(2)
+	}
+
+	// Spot-check a prefix against the reference, bit for bit
+	check := min(*n, 1000)
+	for i := 0; i < check; i++ {
+		if final[i] != reference(start[i], *steps) {
+			fmt.Fprintf(os.Stderr, "%s: particle %d diverges from the reference\n", *variant, i)
+			exit(1)
+		}
+	}
+
+	var checksum float64 = 0
+	for _, p := range final {
+		checksum += p.x + p.y + p.z
+	}
+	if math.IsNaN(checksum) {
+		fmt.Fprintf(os.Stderr, "%s: checksum is NaN\n", *variant)
+		exit(1)
+	}
+
+	fmt.Printf("Particle checksum (%s): %.6f\n", *variant, checksum)
+	reportKernel("particles", *variant, elapsed, fmt.Sprintf("%.6f", checksum))
+}
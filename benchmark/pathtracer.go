@@ -0,0 +1,278 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"time"
+)
+
+// A port of Kevin Beason's smallpt: a Cornell box with a mirror and a
+// glass sphere, lit by one huge spherical light, rendered by unbiased
+// Monte Carlo path tracing. The scene and shading follow the original;
+// randomness comes from one LCG stream per image row.
+
+type vec struct {
+	x, y, z float64
+}
+
+func (a vec) add(b vec) vec       { return vec{a.x + b.x, a.y + b.y, a.z + b.z} }
+func (a vec) sub(b vec) vec       { return vec{a.x - b.x, a.y - b.y, a.z - b.z} }
+func (a vec) scale(s float64) vec { return vec{a.x * s, a.y * s, a.z * s} }
+func (a vec) mult(b vec) vec      { return vec{a.x * b.x, a.y * b.y, a.z * b.z} }
+func (a vec) dot(b vec) float64   { return a.x*b.x + a.y*b.y + a.z*b.z }
+func (a vec) norm() vec           { return a.scale(1 / math.Sqrt(a.dot(a))) }
+func (a vec) cross(b vec) vec {
+	return vec{a.y*b.z - a.z*b.y, a.z*b.x - a.x*b.z, a.x*b.y - a.y*b.x}
+}
+
+type ray struct {
+	o, d vec
+}
+
+type material int
+
+const (
+	diffuse material = iota
+	specular
+	refractive
+)
+
+type sphere struct {
+	rad      float64
+	p, e, c  vec
+	material material
+}
+
+// Returns the distance along r to the nearest hit, or 0 for a miss.
+func (s *sphere) intersect(r ray) float64 {
+	const eps = 1e-4
+	op := s.p.sub(r.o)
+	b := op.dot(r.d)
+	det := b*b - op.dot(op) + s.rad*s.rad
+	if det < 0 {
+		return 0
+	}
+	det = math.Sqrt(det)
+	if t := b - det; t > eps {
+		return t
+	}
+	if t := b + det; t > eps {
+		return t
+	}
+	return 0
+}
+
+var scene = []sphere{
+	{1e5, vec{1e5 + 1, 40.8, 81.6}, vec{}, vec{.75, .25, .25}, diffuse},   // Left
+	{1e5, vec{-1e5 + 99, 40.8, 81.6}, vec{}, vec{.25, .25, .75}, diffuse}, // Right
+	{1e5, vec{50, 40.8, 1e5}, vec{}, vec{.75, .75, .75}, diffuse},         // Back
+	{1e5, vec{50, 40.8, -1e5 + 170}, vec{}, vec{}, diffuse},               // Front
+	{1e5, vec{50, 1e5, 81.6}, vec{}, vec{.75, .75, .75}, diffuse},         // Bottom
+	{1e5, vec{50, -1e5 + 81.6, 81.6}, vec{}, vec{.75, .75, .75}, diffuse}, // Top
+	{16.5, vec{27, 16.5, 47}, vec{}, vec{.999, .999, .999}, specular},     // Mirror
+	{16.5, vec{73, 16.5, 78}, vec{}, vec{.999, .999, .999}, refractive},   // Glass
+	{600, vec{50, 681.6 - .27, 81.6}, vec{12, 12, 12}, vec{}, diffuse},    // Light
+}
+
+func intersect(r ray) (float64, int) {
+	t, id := math.Inf(1), -1
+	for i := range scene {
+		if d := scene[i].intersect(r); d != 0 && d < t {
+			t, id = d, i
+		}
+	}
+	return t, id
+}
+
+func radiance(r ray, depth int, rng *lcg) vec {
+	t, id := intersect(r)
+	if id < 0 {
+		return vec{}
+	}
+	obj := &scene[id]
+	x := r.o.add(r.d.scale(t))
+	n := x.sub(obj.p).norm()
+	nl := n
+	if n.dot(r.d) >= 0 {
+		nl = n.scale(-1)
+	}
+	f := obj.c
+
+	// Russian roulette after 5 bounces, weighted by the max reflectance
+	p := max(f.x, f.y, f.z)
+	depth++
+	if depth > 5 {
+		if rng.float() < p {
+			f = f.scale(1 / p)
+		} else {
+			return obj.e
+		}
+	}
+
+	switch obj.material {
+	case diffuse:
+		// Cosine-weighted hemisphere sample around nl
+		r1 := 2 * math.Pi * rng.float()
+		r2 := rng.float()
+		r2s := math.Sqrt(r2)
+		w := nl
+		u := vec{1, 0, 0}
+		if math.Abs(w.x) > .1 {
+			u = vec{0, 1, 0}
+		}
+		u = u.cross(w).norm()
+		v := w.cross(u)
+		d := u.scale(math.Cos(r1) * r2s).add(v.scale(math.Sin(r1) * r2s)).add(w.scale(math.Sqrt(1 - r2))).norm()
+		return obj.e.add(f.mult(radiance(ray{x, d}, depth, rng)))
+	case specular:
+		return obj.e.add(f.mult(radiance(ray{x, r.d.sub(n.scale(2 * n.dot(r.d)))}, depth, rng)))
+	}
+
+	// Dielectric: Fresnel-weighted mix of reflection and refraction
+	reflRay := ray{x, r.d.sub(n.scale(2 * n.dot(r.d)))}
+	into := n.dot(nl) > 0
+	nc, nt := 1.0, 1.5
+	nnt := nt / nc
+	if into {
+		nnt = nc / nt
+	}
+	ddn := r.d.dot(nl)
+	cos2t := 1 - nnt*nnt*(1-ddn*ddn)
+	if cos2t < 0 {
+		// Total internal reflection
+		return obj.e.add(f.mult(radiance(reflRay, depth, rng)))
+	}
+	sign := -1.0
+	if into {
+		sign = 1.0
+	}
+	tdir := r.d.scale(nnt).sub(n.scale(sign * (ddn*nnt + math.Sqrt(cos2t)))).norm()
+	a, b := nt-nc, nt+nc
+	r0 := a * a / (b * b)
+	c := 1 - tdir.dot(n)
+	if into {
+		c = 1 + ddn
+	}
+	re := r0 + (1-r0)*c*c*c*c*c
+	tr := 1 - re
+	pr := .25 + .5*re
+	rp, tp := re/pr, tr/(1-pr)
+
+	var l vec
+	if depth > 2 {
+		if rng.float() < pr {
+			l = radiance(reflRay, depth, rng).scale(rp)
+		} else {
+			l = radiance(ray{x, tdir}, depth, rng).scale(tp)
+		}
+	} else {
+		l = radiance(reflRay, depth, rng).scale(re).add(radiance(ray{x, tdir}, depth, rng).scale(tr))
+	}
+	return obj.e.add(f.mult(l))
+}
+
+func clamp(x float64) float64 {
+	return min(max(x, 0), 1)
+}
+
+// Gamma 2.2 and quantize to 8 bits.
+func toInt(x float64) int64 {
+	return int64(math.Pow(clamp(x), 1/2.2)*255 + .5)
+}
+
+// Renders w*h pixels, each as 2x2 subpixels with samps samples apiece
+// and a tent filter, exactly like smallpt. Row 0 is the bottom.
+func render(w, h, samps int, seed int64) []vec {
+	cam := ray{vec{50, 52, 295.6}, vec{0, -0.042612, -1}.norm()}
+	cx := vec{float64(w) * .5135 / float64(h), 0, 0}
+	cy := cx.cross(cam.d).norm().scale(.5135)
+	img := make([]vec, w*h)
+
+	for y := 0; y < h; y++ {
+		rng := newLCG(streamSeed(seed, y))
+		for x := 0; x < w; x++ {
+			i := (h-y-1)*w + x
+			for sy := 0; sy < 2; sy++ {
+				for sx := 0; sx < 2; sx++ {
+					var r vec
+					for s := 0; s < samps; s++ {
+						r1 := 2 * rng.float()
+						dx := 1 - math.Sqrt(2-r1)
+						if r1 < 1 {
+							dx = math.Sqrt(r1) - 1
+						}
+						r2 := 2 * rng.float()
+						dy := 1 - math.Sqrt(2-r2)
+						if r2 < 1 {
+							dy = math.Sqrt(r2) - 1
+						}
+						d := cx.scale(((float64(sx)+.5+dx)/2+float64(x))/float64(w) - .5).
+							add(cy.scale(((float64(sy)+.5+dy)/2+float64(y))/float64(h) - .5)).
+							add(cam.d)
+						r = r.add(radiance(ray{cam.o.add(d.scale(140)), d.norm()}, 0, rng).scale(1 / float64(samps)))
+					}
+					img[i] = img[i].add(vec{clamp(r.x), clamp(r.y), clamp(r.z)}.scale(.25))
+				}
+			}
+		}
+	}
+	return img
+}
+
+func writePPM(path string, img []vec, w, h int) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	out := bufio.NewWriter(f)
+	fmt.Fprintf(out, "P3\n%d %d\n255\n", w, h)
+	for _, c := range img {
+		fmt.Fprintf(out, "%d %d %d ", toInt(c.x), toInt(c.y), toInt(c.z))
+	}
+	return out.Flush()
+}
+
+var presets = sizePresets{
+	"small":  {"w": "128", "h": "96"},
+	"medium": {"w": "256", "h": "192"},
+	"large":  {"w": "512", "h": "384"},
+	"huge":   {"w": "1024", "h": "768"},
+}
+
+func main() {
+	w := flag.Int("w", 256, "image width")
+	h := flag.Int("h", 192, "image height")
+	samps := flag.Int("samples", 4, "samples per subpixel (4 subpixels per pixel)")
+	seed := flag.Int64("seed", 42, "LCG seed")
+	out := flag.String("o", "", "write the image to this PPM file")
+	parseWithSize(presets)
+	defer startProfiling()()
+
+	if *w < 1 || *h < 1 || *samps < 1 {
+		fmt.Fprintln(os.Stderr, "-w, -h and -samples must be at least 1")
+		exit(2)
+	}
+
+	start := startKernel()
+	img := render(*w, *h, *samps, *seed)
+	elapsed := time.Since(start)
+
+	var checksum int64 = 0
+	for _, c := range img {
+		checksum += toInt(c.x) + toInt(c.y) + toInt(c.z)
+	}
+
+	if *out != "" {
+		if err := writePPM(*out, img, *w, *h); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			exit(1)
+		}
+	}
+
+	fmt.Printf("Path tracer %dx%d, %d spp, image checksum: %d\n", *w, *h, *samps*4, checksum)
+	reportKernel("pathtracer", defaultVariant, elapsed, checksum)
+}
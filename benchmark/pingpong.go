@@ -0,0 +1,92 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// Each pair bounces a token between two goroutines: ping sends it, pong
+// adds one and sends it back. The token is a running count of hops, so the
+// final values check that no message was lost or duplicated.
+
+func pair(rounds int64, buffer int) int64 {
+	ping := make(chan int64, buffer)
+	pong := make(chan int64, buffer)
+
+	go func() {
+		for tok := range ping {
+			pong <- tok + 1
+		}
+		close(pong)
+	}()
+
+	var tok int64 = 0
+	for i := int64(0); i < rounds; i++ {
+		ping <- tok + 1
+		tok = <-pong
+	}
+	close(ping)
+	return tok
+}
+
+var presets = sizePresets{
+	"small":  {"n": "200000"},
+	"medium": {"n": "2000000"},
+	"large":  {"n": "10000000"},
+	"huge":   {"n": "50000000"},
+}
+
+func main() {
+	rounds := flag.Int64("n", 2_000_000, "round trips per pair")
+	pairs := flag.Int("pairs", 1, "goroutine pairs running at once")
+	variant := flag.String("variant", "unbuffered", "unbuffered or buffered")
+	parseWithSize(presets)
+	defer startProfiling()()
+
+	if *rounds < 0 || *pairs < 1 {
+		fmt.Fprintln(os.Stderr, "-n must be non-negative and -pairs at least 1")
+		exit(2)
+	}
+
+	var buffer int
+	switch *variant {
+	case "unbuffered":
+		buffer = 0
+	case "buffered":
+		buffer = 1
+	default:
+		fmt.Fprintf(os.Stderr, "unknown variant %q\n", *variant)
+		exit(2)
+	}
+
+	start := startKernel()
+	tokens := make([]int64, *pairs)
+	var wg sync.WaitGroup
+	for p := range tokens {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			tokens[p] = pair(*rounds, buffer)
+		}()
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	var hops int64 = 0
+	for p, tok := range tokens {
+		if tok != 2**rounds {
+			fmt.Fprintf(os.Stderr, "pair %d ended with token %d, want %d\n", p, tok, 2**rounds)
+			exit(1)
+		}
+		hops += tok
+	}
+
+	fmt.Printf("Channel ping-pong (%s, %d pairs): %d messages\n", *variant, *pairs, hops)
+
+	// Throughput depends on the machine, so it stays off stdout
+	fmt.Fprintf(os.Stderr, "messages/s: %.0f\n", float64(hops)/elapsed.Seconds())
+	reportKernel("pingpong", *variant, elapsed, hops)
+}
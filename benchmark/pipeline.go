@@ -0,0 +1,115 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+)
+
+// generate -> transform -> filter -> reduce, written twice: once as four
+// goroutines joined by channels, once as a single loop doing the same
+// work per value. Channels keep order and there's one reducer, so both
+// add the same values in the same order whatever GOMAXPROCS is.
+
+func transform(x int64) int64 {
+	return (x*x + 7) % 1_000_003
+}
+
+func keep(x int64) bool {
+	return x%3 != 0
+}
+
+func pipelineChannels(n, seed int64, buffer int) int64 {
+	generated := make(chan int64, buffer)
+	transformed := make(chan int64, buffer)
+	filtered := make(chan int64, buffer)
+
+	go func() {
+		r := newLCG(seed)
+		for i := int64(0); i < n; i++ {
+			generated <- r.below(1 << 20)
+		}
+		close(generated)
+	}()
+	go func() {
+		for x := range generated {
+			transformed <- transform(x)
+		}
+		close(transformed)
+	}()
+	go func() {
+		for x := range transformed {
+			if keep(x) {
+				filtered <- x
+			}
+		}
+		close(filtered)
+	}()
+
+	var sum int64 = 0
+	for x := range filtered {
+		sum += x
+	}
+	return sum
+}
+
+func pipelineFused(n, seed int64) int64 {
+	r := newLCG(seed)
+	var sum int64 = 0
+	for i := int64(0); i < n; i++ {
+		if x := transform(r.below(1 << 20)); keep(x) {
+			sum += x
+		}
+	}
+	return sum
+}
+
+var presets = sizePresets{
+	"small":  {"n": "500000"},
+	"medium": {"n": "5000000"},
+	"large":  {"n": "20000000"},
+	"huge":   {"n": "100000000"},
+}
+
+func main() {
+	n := flag.Int64("n", 5_000_000, "values pushed through the pipeline")
+	buffer := flag.Int("buffer", 64, "channel capacity between stages")
+	seed := flag.Int64("seed", 42, "LCG seed for the generator")
+	variant := flag.String("variant", "channels", "channels or fused")
+	verify := flag.Bool("verify", false, "check the sum against expected.go")
+	parseWithSize(presets)
+	defer startProfiling()()
+
+	if *n < 0 || *buffer < 0 {
+		fmt.Fprintln(os.Stderr, "-n and -buffer must be non-negative")
+		exit(2)
+	}
+
+	var run func() int64
+	switch *variant {
+	case "channels":
+		run = func() int64 { return pipelineChannels(*n, *seed, *buffer) }
+	case "fused":
+		run = func() int64 { return pipelineFused(*n, *seed) }
+	default:
+		fmt.Fprintf(os.Stderr, "unknown variant %q\n", *variant)
+		exit(2)
+	}
+
+	// Both shapes must agree on a small prefix before the real run
+	check := min(*n, 10_000)
+	if c, f := pipelineChannels(check, *seed, *buffer), pipelineFused(check, *seed); c != f {
+		fmt.Fprintf(os.Stderr, "channels and fused disagree on %d values: %d vs %d\n", check, c, f)
+		exit(1)
+	}
+
+	start := startKernel()
+	sum := run()
+	elapsed := time.Since(start)
+	fmt.Printf("Pipeline sum (%s): %d\n", *variant, sum)
+	if *verify {
+		verifyResult("pipeline", fmt.Sprintf("n=%d seed=%d", *n, *seed), sum)
+	}
+	reportKernel("pipeline", *variant, elapsed, sum)
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"testing"
+)
+
+func TestPipelineExpected(t *testing.T) {
+	testExpected(t, "pipeline", "n", 5_000_000, func(t *testing.T, c expectedCase) int64 {
+		return pipelineFused(c.int(t, "n"), c.int(t, "seed"))
+	})
+}
+
+// The channel stages give the fused sum at every GOMAXPROCS and buffer
+// size, unbuffered included.
+func TestPipelineChannels(t *testing.T) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(0))
+	for _, n := range []int64{0, 1, 1000, 200_000} {
+		want := pipelineFused(n, 42)
+		for _, procs := range []int{1, 2, 4, 8} {
+			runtime.GOMAXPROCS(procs)
+			for _, buffer := range []int{0, 1, 64, 1024} {
+				t.Run(fmt.Sprintf("n=%d/procs=%d/buffer=%d", n, procs, buffer), func(t *testing.T) {
+					if got := pipelineChannels(n, 42, buffer); got != want {
+						t.Errorf("got %d, fused has %d", got, want)
+					}
+				})
+			}
+		}
+	}
+}
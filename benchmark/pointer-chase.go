@@ -0,0 +1,98 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Builds next[] as a single cycle through all n slots.
+// The sequential layout links i to i+1, which hardware prefetchers
+// follow easily; the random one uses Sattolo's shuffle, so every hop
+// is a cache miss the CPU can't predict.
+func buildChain(n int, random bool, seed int64) []int64 {
+	order := make([]int64, n)
+	for i := range order {
+		order[i] = int64(i)
+	}
+	if random {
+		r := newLCG(seed)
+		// Sattolo: j < i (not j <= i) guarantees one cycle of length n
+		for i := n - 1; i > 0; i-- {
+			j := r.below(int64(i))
+			order[i], order[j] = order[j], order[i]
+		}
+	}
+
+	next := make([]int64, n)
+	for i := 0; i < n; i++ {
+		next[order[i]] = order[(i+1)%n]
+	}
+	return next
+}
+
+// Each load depends on the previous one, so this runs at memory latency.
+func chase(next []int64, steps int64) (int64, int64) {
+	var pos int64 = 0
+	var acc int64 = 0
+	for s := int64(0); s < steps; s++ {
+		acc += pos
+		pos = next[pos]
+	}
+	return pos, acc
+}
+
+var presets = sizePresets{
+	"small":  {"n": "262144"},
+	"medium": {"n": "4194304"},
+	"large":  {"n": "16777216"},
+	"huge":   {"n": "67108864"},
+}
+
+func main() {
+	n := flag.Int("n", 1<<22, "number of nodes in the chain")
+	laps := flag.Int64("laps", 4, "full traversals of the chain")
+	seed := flag.Int64("seed", 42, "LCG seed for the shuffle")
+	variant := flag.String("variant", "random", "random or sequential")
+	parseWithSize(presets)
+	defer startProfiling()()
+
+	if *n < 1 || *laps < 1 {
+		fmt.Fprintln(os.Stderr, "-n and -laps must be at least 1")
+		exit(2)
+	}
+
+	var next []int64
+	switch *variant {
+	case "random":
+		next = buildChain(*n, true, *seed)
+	case "sequential":
+		next = buildChain(*n, false, *seed)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown variant %q\n", *variant)
+		exit(2)
+	}
+
+	nodes := int64(*n)
+	start := startKernel()
+	pos, acc := chase(next, *laps*nodes)
+	elapsed := time.Since(start)
+
+	// A full lap visits every node once, whatever the order,
+	// so we end where we started and the sum is known.
+	expected := *laps * (nodes * (nodes - 1) / 2)
+	if pos != 0 || acc != expected {
+		fmt.Fprintf(os.Stderr, "%s: ended at %d with sum %d, expected 0 and %d\n",
+			*variant, pos, acc, expected)
+		exit(1)
+	}
+
+	// The loads are dependent, so time per hop is the load latency of
+	// wherever an n-node chain fits: sweep -n across the cache sizes for
+	// the L1/L2/L3/DRAM curve.
+	perHop := float64(elapsed.Nanoseconds()) / float64(*laps*nodes)
+	fmt.Printf("Pointer chase sum (%s): %d\n", *variant, acc)
+	fmt.Printf("Latency (%s): %.2f ns/hop\n", *variant, perHop)
+	reportKernel("pointer-chase", *variant, elapsed, acc, fmt.Sprintf("ns_per_hop=%.2f", perHop))
+}
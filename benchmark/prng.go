@@ -0,0 +1,83 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+)
+
+// First outputs of pcg32_srandom_r(42, 54), from the PCG reference demo.
+var pcgReference = []uint32{0xa15c02b7, 0x7b47f409, 0xba1d3330, 0x83d2f293, 0xbfa4784b, 0xcbed606e}
+
+// Each loop draws n values and sums them, so the work can't be elided
+// and the sum doubles as a checksum.
+
+func sumLCG(n, seed int64) uint64 {
+	r := newLCG(seed)
+	var acc uint64 = 0
+	for i := int64(0); i < n; i++ {
+		acc += uint64(r.next())
+	}
+	return acc
+}
+
+func sumXorshift(n, seed int64) uint64 {
+	r := newXorshift(seed)
+	var acc uint64 = 0
+	for i := int64(0); i < n; i++ {
+		acc += r.next()
+	}
+	return acc
+}
+
+func sumPCG(n, seed int64) uint64 {
+	r := newPCG32(uint64(seed), 54)
+	var acc uint64 = 0
+	for i := int64(0); i < n; i++ {
+		acc += uint64(r.next())
+	}
+	return acc
+}
+
+var presets = sizePresets{
+	"small":  {"n": "50000000"},
+	"medium": {"n": "500000000"},
+	"large":  {"n": "2000000000"},
+	"huge":   {"n": "10000000000"},
+}
+
+func main() {
+	n := flag.Int64("n", 500_000_000, "values to draw")
+	seed := flag.Int64("seed", 42, "generator seed")
+	variant := flag.String("variant", "lcg", "lcg, xorshift or pcg")
+	parseWithSize(presets)
+	defer startProfiling()()
+
+	var draw func(int64, int64) uint64
+	switch *variant {
+	case "lcg":
+		draw = sumLCG
+	case "xorshift":
+		draw = sumXorshift
+	case "pcg":
+		draw = sumPCG
+	default:
+		fmt.Fprintf(os.Stderr, "unknown variant %q\n", *variant)
+		exit(2)
+	}
+
+	p := newPCG32(42, 54)
+	for i, want := range pcgReference {
+		if got := p.next(); got != want {
+			fmt.Fprintf(os.Stderr, "pcg32 output %d is %08x, reference says %08x\n", i, got, want)
+			exit(1)
+		}
+	}
+
+	start := startKernel()
+	sum := draw(*n, *seed)
+	elapsed := time.Since(start)
+	fmt.Printf("PRNG sum (%s): %d\n", *variant, sum)
+	reportKernel("prng", *variant, elapsed, sum)
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"runtime/metrics"
+	"runtime/pprof"
+	rtrace "runtime/trace"
+	"time"
+)
+
+// Profiling hooks shared by every benchmark. Each main calls
+//
+//	defer startProfiling()()
+//
+// right after parsing flags, and exits through exit rather than os.Exit.
+// `bench pgo` uses -cpuprofile to collect the profile it rebuilds with.
+//
+// -trace writes a runtime/trace execution trace for go tool trace, to
+// see the scheduler, GC and syscalls of IO-heavy benchmarks. It starts
+// at the first startKernel rather than here, so setup stays out of it,
+// and each timed region shows up as a "kernel" region.
+//
+// -mem samples stack and heap use from startKernel to reportKernel and
+// adds the peaks to the MMLBENCH line: stack_peak is how far goroutine
+// stacks grew past what they had at the start, heap_peak the most live
+// heap seen. Those are what the recursive benchmarks (ackermann,
+// nqueens) and json-scan's nesting need, and so what an MML target
+// without growable stacks has to provide up front.
+//
+// -noop is checked here too, since this is the first thing every main
+// runs after parsing: it prints one line and exits, so `bench size` can
+// time process startup to first output without any of the workload.
+
+var (
+	cpuProfile = flag.String("cpuprofile", "", "write a CPU profile to this file")
+	traceFile  = flag.String("trace", "", "write an execution trace of the timed region to this file")
+	noop       = flag.Bool("noop", false, "print one line and exit before doing any work")
+	memPeaks   = flag.Bool("mem", false, "report peak stack growth and heap use of the timed region")
+)
+
+var tracing *os.File
+
+// The stop function of startProfiling, for exit.
+var stopProfiling = func() {}
+
+func startProfiling() (stop func()) {
+	if *noop {
+		fmt.Println("noop")
+		os.Exit(0)
+	}
+	var f *os.File
+	if *cpuProfile != "" {
+		var err error
+		if f, err = os.Create(*cpuProfile); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		if err := pprof.StartCPUProfile(f); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+	}
+	stopProfiling = func() {
+		if f != nil {
+			pprof.StopCPUProfile()
+			f.Close()
+			f = nil
+		}
+		if tracing != nil {
+			rtrace.Stop()
+			tracing.Close()
+			tracing = nil
+		}
+	}
+	return stopProfiling
+}
+
+// exit is os.Exit for everything after startProfiling. os.Exit skips the
+// deferred stop, which would leave the -cpuprofile and -trace files cut
+// short on exactly the runs that fail a check, so exit stops them first.
+func exit(code int) {
+	stopProfiling()
+	os.Exit(code)
+}
+
+// The -mem sampler. runtime/metrics reads don't stop the world, so
+// sampling every 100µs barely touches the kernel; on a single CPU the
+// sampler only gets in at preemption points, which is why stopMemWatch
+// takes a last sample too (stacks only shrink at a GC).
+type memWatch struct {
+	samples             []metrics.Sample
+	stackBase           uint64
+	stackPeak, heapPeak uint64
+	done                chan struct{}
+	stopped             chan struct{}
+}
+
+var watching *memWatch
+
+func (w *memWatch) sample() (stack, heap uint64) {
+	metrics.Read(w.samples)
+	return w.samples[0].Value.Uint64(), w.samples[1].Value.Uint64()
+}
+
+func (w *memWatch) update() {
+	stack, heap := w.sample()
+	w.stackPeak = max(w.stackPeak, stack)
+	w.heapPeak = max(w.heapPeak, heap)
+}
+
+// startMemWatch starts the -mem sampler once; later calls do nothing.
+func startMemWatch() {
+	if !*memPeaks || watching != nil {
+		return
+	}
+	w := &memWatch{
+		samples: []metrics.Sample{
+			{Name: "/memory/classes/heap/stacks:bytes"},
+			{Name: "/memory/classes/heap/objects:bytes"},
+		},
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	w.stackBase, _ = w.sample()
+	w.update()
+	go func() {
+		defer close(w.stopped)
+		tick := time.NewTicker(100 * time.Microsecond)
+		defer tick.Stop()
+		for {
+			select {
+			case <-w.done:
+				return
+			case <-tick.C:
+				w.update()
+			}
+		}
+	}()
+	watching = w
+}
+
+// stopMemWatch returns the MMLBENCH fields for the peaks, or "" without -mem.
+func stopMemWatch() string {
+	w := watching
+	if w == nil {
+		return ""
+	}
+	watching = nil
+	close(w.done)
+	<-w.stopped
+	w.update()
+	return fmt.Sprintf(" stack_peak=%d heap_peak=%d", w.stackPeak-min(w.stackBase, w.stackPeak), w.heapPeak)
+}
+
+// startTrace starts the -trace trace once; later calls do nothing.
+func startTrace() {
+	if *traceFile == "" || tracing != nil {
+		return
+	}
+	f, err := os.Create(*traceFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if err := rtrace.Start(f); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	tracing = f
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+)
+
+const (
+	radixBits    = 8
+	radixBuckets = 1 << radixBits
+	radixPasses  = 64 / radixBits
+)
+
+// LSD radix sort, one byte per pass.
+// All eight histograms are built in a single read of the input,
+// then each pass is a pure scatter between arr and a scratch buffer.
+// Passes where every key shares the same digit are skipped.
+func radixSort(arr []uint64) {
+	var counts [radixPasses][radixBuckets]int
+	for _, v := range arr {
+		for p := 0; p < radixPasses; p++ {
+			counts[p][(v>>(p*radixBits))&(radixBuckets-1)]++
+		}
+	}
+
+	src := arr
+	dst := make([]uint64, len(arr))
+	for p := 0; p < radixPasses; p++ {
+		c := &counts[p]
+		if c[(src[0]>>(p*radixBits))&(radixBuckets-1)] == len(src) {
+			continue
+		}
+
+		// Exclusive prefix sum: counts become starting offsets
+		offset := 0
+		for b := range c {
+			n := c[b]
+			c[b] = offset
+			offset += n
+		}
+
+		shift := p * radixBits
+		for _, v := range src {
+			b := (v >> shift) & (radixBuckets - 1)
+			dst[c[b]] = v
+			c[b]++
+		}
+		src, dst = dst, src
+	}
+
+	// An odd number of executed passes leaves the result in the scratch buffer
+	if &src[0] != &arr[0] {
+		copy(arr, src)
+	}
+}
+
+func sum(arr []uint64) uint64 {
+	var acc uint64 = 0
+	for _, v := range arr {
+		acc += v
+	}
+	return acc
+}
+
+// Position-weighted sum: identical only if the order is identical.
+func checksum(arr []uint64) uint64 {
+	var acc uint64 = 0
+	for i, v := range arr {
+		acc += v * uint64(i+1)
+	}
+	return acc
+}
+
+var presets = sizePresets{
+	"small":  {"n": "1000000"},
+	"medium": {"n": "10000000"},
+	"large":  {"n": "50000000"},
+	"huge":   {"n": "200000000"},
+}
+
+func main() {
+	n := flag.Int("n", 10_000_000, "number of keys to sort")
+	seed := flag.Int64("seed", 42, "LCG seed for the input")
+	variant := flag.String("variant", "radix", "radix or slices")
+	parseWithSize(presets)
+	defer startProfiling()()
+
+	if *n < 1 {
+		fmt.Fprintln(os.Stderr, "-n must be at least 1")
+		exit(2)
+	}
+
+	arr := make([]uint64, *n)
+	r := newLCG(*seed)
+	for i := range arr {
+		arr[i] = uint64(r.next())
+	}
+	before := sum(arr)
+
+	start := startKernel()
+	switch *variant {
+	case "radix":
+		radixSort(arr)
+	case "slices":
+		slices.Sort(arr)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown variant %q\n", *variant)
+		exit(2)
+	}
+	elapsed := time.Since(start)
+
+	if !slices.IsSorted(arr) {
+		fmt.Fprintf(os.Stderr, "%s: output is not sorted\n", *variant)
+		exit(1)
+	}
+	if sum(arr) != before {
+		fmt.Fprintf(os.Stderr, "%s: keys changed while sorting\n", *variant)
+		exit(1)
+	}
+
+	sorted := checksum(arr)
+	fmt.Printf("Radix checksum (%s): %d\n", *variant, sorted)
+	reportKernel("radixsort", *variant, elapsed, sorted)
+}
@@ -0,0 +1,97 @@
+package main
+
+// Seeded generators shared by the Go benchmarks.
+// The LCG is what input generation uses everywhere; xorshift64* and
+// PCG32 are here for comparison (see prng.go).
+// This file has no main; build it together with the benchmark, e.g.
+//   go build -o bin/sort-go sort.go rng.go
+
+// lcg is the same generator fillMatrix and quicksort.c use:
+// next = prev*1664525 + 1013904223, wrapping on int64 overflow.
+type lcg struct {
+	state int64
+}
+
+func newLCG(seed int64) *lcg {
+	return &lcg{state: seed}
+}
+
+func (r *lcg) next() int64 {
+	r.state = (r.state * 1664525) + 1013904223
+	return r.state
+}
+
+// below returns a value in [0, n) taken from the high bits,
+// since the low bits of an LCG have short periods.
+func (r *lcg) below(n int64) int64 {
+	return int64((uint64(r.next()) >> 33) % uint64(n))
+}
+
+// float returns a value in [0, 1) built from the top 53 bits.
+func (r *lcg) float() float64 {
+	return float64(uint64(r.next())>>11) / (1 << 53)
+}
+
+// xorshift64* (Vigna): xorshift state, multiplied on output.
+// The state must never be zero.
+type xorshift64s struct {
+	state uint64
+}
+
+func newXorshift(seed int64) *xorshift64s {
+	s := uint64(seed)
+	if s == 0 {
+		s = 0x9E3779B97F4A7C15
+	}
+	return &xorshift64s{state: s}
+}
+
+func (r *xorshift64s) next() uint64 {
+	r.state ^= r.state >> 12
+	r.state ^= r.state << 25
+	r.state ^= r.state >> 27
+	return r.state * 0x2545F4914F6CDD1D
+}
+
+// PCG32 (O'Neill), XSH RR variant: 64-bit LCG state, 32-bit output.
+// Seeding follows pcg32_srandom_r, so outputs match the reference code.
+type pcg32 struct {
+	state, inc uint64
+}
+
+func newPCG32(seed, seq uint64) *pcg32 {
+	r := &pcg32{state: 0, inc: seq<<1 | 1}
+	r.next()
+	r.state += seed
+	r.next()
+	return r
+}
+
+func (r *pcg32) next() uint32 {
+	old := r.state
+	r.state = old*6364136223846793005 + r.inc
+	xorshifted := uint32(((old >> 18) ^ old) >> 27)
+	rot := uint32(old >> 59)
+	return xorshifted>>rot | xorshifted<<((-rot)&31)
+}
+
+// streamSeed derives the seed of an independent substream, for code that
+// splits work into chunks. Nearby LCG seeds give visibly correlated
+// sequences, so the pair is scrambled with the splitmix64 finalizer.
+func streamSeed(seed int64, stream int) int64 {
+	x := uint64(seed) + uint64(stream+1)*0x9E3779B97F4A7C15
+	x ^= x >> 30
+	x *= 0xBF58476D1CE4E5B9
+	x ^= x >> 27
+	x *= 0x94D049BB133111EB
+	x ^= x >> 31
+	return int64(x)
+}
+
+// fillRandom fills arr with raw LCG output starting from seed.
+func fillRandom(arr []int64, seed int64) {
+	r := newLCG(seed)
+	for i := range arr {
+		arr[i] = r.next()
+	}
+}
@@ -1,6 +1,10 @@
 package main
 
-import "fmt"
+import (
+	"flag"
+	"fmt"
+	"time"
+)
 
 func initSieve(arr []int64) {
 	// Optimization 1: Use range loop.
@@ -79,7 +83,32 @@ func runSieve(limit int64) int64 {
 	return countPrimes(arr)
 }
 
+var presets = sizePresets{
+	"small":  {"n": "1000000"},
+	"medium": {"n": "10000000"},
+	"large":  {"n": "100000000"},
+	"huge":   {"n": "1000000000"},
+}
+
 func main() {
-	count := runSieve(1_000_000)
+	parityFlag("sieve.mml")
+	limit := flag.Int64("n", 1_000_000, "count primes up to this")
+	verify := flag.Bool("verify", false, "check the result against expected.go")
+	checks := flag.Int("check", 0, "run this many randomized checks against trial division instead of the benchmark")
+	aliasEnv("LIMIT", "n")
+	parseWithSize(presets)
+	defer startProfiling()()
+
+	if *checks > 0 {
+		runChecks("sieve-opt", *checks, checkSieve(runSieve))
+	}
+
+	start := startKernel()
+	count := runSieve(*limit)
+	elapsed := time.Since(start)
 	fmt.Printf("Primes found: %d\n", count)
+	reportKernel("sieve", "opt", elapsed, count)
+	if *verify {
+		verifyResult("sieve", fmt.Sprintf("limit=%d", *limit), count)
+	}
 }
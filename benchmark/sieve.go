@@ -1,6 +1,11 @@
 package main
 
-import "fmt"
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+)
 
 func initSieve(arr []int64) {
 	// Optimization: Use range to eliminate bounds checks
@@ -52,6 +57,74 @@ func countPrimes(arr []int64) int64 {
 	return count
 }
 
+// Alternative inner loops for micro-optimization experiments, picked with
+// -kernel. They all go through the same -verify and -check, so
+//
+//	bin/sieve-go -kernel unroll -check 1000
+//	bench compare 'bin/sieve-go' 'bin/sieve-go -kernel unroll'
+//
+// is the whole experiment. New ones go in sieveKernels.
+type sieveKernel struct {
+	clear func(arr []int64, factor, num int64)
+	count func(arr []int64) int64
+}
+
+var sieveKernels = map[string]sieveKernel{
+	"default": {clearMultiples, countPrimes},
+	"unroll":  {clearMultiplesUnroll, countPrimes},
+	"stride2": {clearMultiplesStride2, countPrimes},
+	"split":   {clearMultiples, countPrimesSplit},
+}
+
+var kernel = sieveKernels["default"]
+
+// Four stores per trip, then the remainder one at a time.
+func clearMultiplesUnroll(arr []int64, factor, num int64) {
+	size := int64(len(arr))
+	for num+3*factor < size {
+		arr[num] = 0
+		arr[num+factor] = 0
+		arr[num+2*factor] = 0
+		arr[num+3*factor] = 0
+		num += 4 * factor
+	}
+	for num < size {
+		arr[num] = 0
+		num += factor
+	}
+}
+
+// Two cursors a factor apart, each stepping 2*factor, so the stores of a
+// trip don't depend on each other's address.
+func clearMultiplesStride2(arr []int64, factor, num int64) {
+	size := int64(len(arr))
+	step := 2 * factor
+	for next := num + factor; next < size; next += step {
+		arr[num] = 0
+		arr[next] = 0
+		num += step
+	}
+	if num < size {
+		arr[num] = 0
+	}
+}
+
+// Four independent sums, to break the add dependency chain.
+func countPrimesSplit(arr []int64) int64 {
+	var c0, c1, c2, c3 int64
+	i := 0
+	for ; i+3 < len(arr); i += 4 {
+		c0 += arr[i]
+		c1 += arr[i+1]
+		c2 += arr[i+2]
+		c3 += arr[i+3]
+	}
+	for ; i < len(arr); i++ {
+		c0 += arr[i]
+	}
+	return 1 + c0 + c1 + c2 + c3
+}
+
 func runSieve(limit int64) int64 {
 	size := (limit + 1) / 2
 	arr := make([]int64, size)
@@ -71,15 +144,48 @@ func runSieve(limit int64) int64 {
 		actualFactor := next*2 + 1
 		start := actualFactor * actualFactor / 2
 
-		clearMultiples(arr, actualFactor, start)
+		kernel.clear(arr, actualFactor, start)
 
 		factor = actualFactor + 2
 	}
 
-	return countPrimes(arr)
+	return kernel.count(arr)
+}
+
+var presets = sizePresets{
+	"small":  {"n": "1000000"},
+	"medium": {"n": "10000000"},
+	"large":  {"n": "100000000"},
+	"huge":   {"n": "1000000000"},
 }
 
 func main() {
-	count := runSieve(1_000_000)
+	parityFlag("sieve.mml")
+	limit := flag.Int64("n", 1_000_000, "count primes up to this")
+	verify := flag.Bool("verify", false, "check the result against expected.go")
+	checks := flag.Int("check", 0, "run this many randomized checks against trial division instead of the benchmark")
+	kernelName := flag.String("kernel", "default", "inner loops: default, unroll, stride2 or split")
+	aliasEnv("LIMIT", "n")
+	parseWithSize(presets)
+	defer startProfiling()()
+
+	k, ok := sieveKernels[*kernelName]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown kernel %q\n", *kernelName)
+		exit(2)
+	}
+	kernel = k
+
+	if *checks > 0 {
+		runChecks("sieve -kernel "+*kernelName, *checks, checkSieve(runSieve))
+	}
+
+	start := startKernel()
+	count := runSieve(*limit)
+	elapsed := time.Since(start)
 	fmt.Printf("Primes found: %d\n", count)
+	reportKernel("sieve", *kernelName, elapsed, count)
+	if *verify {
+		verifyResult("sieve", fmt.Sprintf("limit=%d", *limit), count)
+	}
 }
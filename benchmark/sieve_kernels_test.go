@@ -0,0 +1,13 @@
+package main
+
+// sieve.go's -kernel alternatives, for the tests in sieve_test.go.
+// runSieve goes through the kernel variable, so each one swaps it in.
+func init() {
+	for name, k := range sieveKernels {
+		sieves[name] = func(limit int64) int64 {
+			defer func(saved sieveKernel) { kernel = saved }(kernel)
+			kernel = k
+			return runSieve(limit)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"maps"
+	"slices"
+	"testing"
+)
+
+// Builds against either sieve, e.g.
+//
+//	go test sieve-opt.go sieve_test.go expected_test.go $(GO_SHARED)
+//
+// sieve.go's -kernel alternatives are added from sieve_kernels_test.go,
+// which only builds with it; make test picks it up.
+
+// The sieves under test, by kernel name.
+var sieves = map[string]func(limit int64) int64{"default": runSieve}
+
+// Runs f as a subtest for each sieve.
+func forEachSieve(t *testing.T, f func(t *testing.T, sieve func(limit int64) int64)) {
+	for _, name := range slices.Sorted(maps.Keys(sieves)) {
+		t.Run(name, func(t *testing.T) { f(t, sieves[name]) })
+	}
+}
+
+func TestSieveChecks(t *testing.T) {
+	forEachSieve(t, func(t *testing.T, sieve func(int64) int64) {
+		check := checkSieve(sieve)
+		for i := 0; i < 300; i++ {
+			if err := check(i, newLCG(streamSeed(checkSeed, i))); err != nil {
+				t.Fatalf("case %d: %v", i, err)
+			}
+		}
+	})
+}
+
+// The count never drops and grows by at most one per step.
+func TestSieveMonotone(t *testing.T) {
+	forEachSieve(t, func(t *testing.T, sieve func(int64) int64) {
+		prev := sieve(2)
+		for limit := int64(3); limit <= 5000; limit++ {
+			got := sieve(limit)
+			if got != prev && got != prev+1 {
+				t.Fatalf("limit=%d: count went from %d to %d", limit, prev, got)
+			}
+			prev = got
+		}
+	})
+}
+
+// Known values of the prime-counting function.
+func TestSievePi(t *testing.T) {
+	forEachSieve(t, func(t *testing.T, sieve func(int64) int64) {
+		for limit, want := range map[int64]int64{
+			2: 1, 10: 4, 100: 25, 1000: 168, 10_000: 1229, 100_000: 9592, 1_000_000: 78498,
+		} {
+			if got := sieve(limit); got != want {
+				t.Errorf("limit=%d: got %d primes, want %d", limit, got, want)
+			}
+		}
+	})
+}
+
+func FuzzSieve(f *testing.F) {
+	for _, limit := range []int64{2, 3, 4, 9, 10, 25, 48, 49, 50, 121, 1000} {
+		f.Add(limit)
+	}
+	f.Fuzz(func(t *testing.T, limit int64) {
+		if limit < 2 || limit > 200_000 {
+			t.Skip()
+		}
+		for name, sieve := range sieves {
+			if err := checkSieveLimit(sieve, limit); err != nil {
+				t.Errorf("%s: %v", name, err)
+			}
+		}
+	})
+}
+
+func TestSieveExpected(t *testing.T) {
+	forEachSieve(t, func(t *testing.T, sieve func(int64) int64) {
+		testExpected(t, "sieve", "limit", 10_000_000, func(t *testing.T, c expectedCase) int64 {
+			return sieve(c.int(t, "limit"))
+		})
+	})
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Named workloads, so runs on different machines or toolchains can say
+// "sieve large" instead of quoting raw numbers. Each benchmark maps the
+// preset names to the flag values they stand for:
+//
+//	var presets = sizePresets{
+//		"small": {"n": "1000000"},
+//		...
+//	}
+//
+// Flags given explicitly on the command line still win over the preset.
+//
+// Any flag can also come from the environment as MMLBENCH_<NAME>, the
+// name upper-cased with dashes as underscores: MMLBENCH_N=1000 for -n,
+// MMLBENCH_SIZE=large for -size. MML binaries can't parse argv yet, so
+// this is how a driver gives both sides the same parameters. The command
+// line wins over the environment, which wins over the preset.
+
+type sizePresets map[string]map[string]string
+
+var sizeNames = []string{"small", "medium", "large", "huge"}
+
+const envPrefix = "MMLBENCH_"
+
+// Extra variable names for flags, where the MML side calls a parameter
+// something else (MMLBENCH_LIMIT for the sieves' -n).
+var envAliases = map[string]string{}
+
+func aliasEnv(name, flagName string) {
+	envAliases[envPrefix+name] = flagName
+}
+
+// parityFlag registers -parity for a benchmark whose output already
+// matches its MML counterpart mml byte for byte. It changes nothing; it's
+// accepted so the parity check can pass it to every benchmark alike.
+func parityFlag(mml string) {
+	flag.Bool("parity", false, "print exactly what "+mml+" prints (the default here)")
+}
+
+// parseFlags is flag.Parse plus the environment: every flag not on the
+// command line is set from its MMLBENCH_ variable if there is one.
+func parseFlags() {
+	flag.Parse()
+	set := map[string]bool{}
+	flag.Visit(func(f *flag.Flag) { set[f.Name] = true })
+
+	fromEnv := func(env, name string) {
+		value, ok := os.LookupEnv(env)
+		if !ok || set[name] {
+			return
+		}
+		if err := flag.Set(name, value); err != nil {
+			fmt.Fprintf(os.Stderr, "%s=%s: %v\n", env, value, err)
+			os.Exit(2)
+		}
+		set[name] = true
+	}
+	var names []string
+	flag.VisitAll(func(f *flag.Flag) { names = append(names, f.Name) })
+	for _, name := range names {
+		fromEnv(envPrefix+strings.ToUpper(strings.ReplaceAll(name, "-", "_")), name)
+	}
+	// The flag's own variable wins over an alias
+	for env, name := range envAliases {
+		fromEnv(env, name)
+	}
+}
+
+// parseWithSize is parseFlags plus -size: it registers the flag, parses
+// the command line and environment, then applies the chosen preset to
+// every flag neither of them set.
+func parseWithSize(presets sizePresets) {
+	size := flag.String("size", "", "named workload: small, medium, large or huge")
+	parseFlags()
+	if *size == "" {
+		return
+	}
+
+	preset, ok := presets[*size]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown size %q, want one of %v\n", *size, sizeNames)
+		os.Exit(2)
+	}
+	given := map[string]bool{}
+	flag.Visit(func(f *flag.Flag) { given[f.Name] = true })
+	for name, value := range preset {
+		if given[name] {
+			continue
+		}
+		if err := flag.Set(name, value); err != nil {
+			// A typo in a preset table, not a user error
+			panic(fmt.Sprintf("size %s: -%s=%s: %v", *size, name, value, err))
+		}
+	}
+}
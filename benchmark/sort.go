@@ -0,0 +1,231 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"time"
+)
+
+// Below this size the recursive sorts switch to insertion sort.
+const insertionCutoff = 16
+
+func insertionSort(arr []int64) {
+	for i := 1; i < len(arr); i++ {
+		v := arr[i]
+		j := i - 1
+		for j >= 0 && arr[j] > v {
+			arr[j+1] = arr[j]
+			j--
+		}
+		arr[j+1] = v
+	}
+}
+
+// Quicksort: Hoare partition around a median-of-three pivot.
+// Recurses on the smaller half and loops on the larger one,
+// so stack depth stays O(log n).
+func quickSort(arr []int64) {
+	for len(arr) > insertionCutoff {
+		mid := len(arr) / 2
+		last := len(arr) - 1
+		if arr[mid] < arr[0] {
+			arr[mid], arr[0] = arr[0], arr[mid]
+		}
+		if arr[last] < arr[0] {
+			arr[last], arr[0] = arr[0], arr[last]
+		}
+		if arr[last] < arr[mid] {
+			arr[last], arr[mid] = arr[mid], arr[last]
+		}
+		pivot := arr[mid]
+
+		i, j := 0, last
+		for {
+			for arr[i] < pivot {
+				i++
+			}
+			for arr[j] > pivot {
+				j--
+			}
+			if i >= j {
+				break
+			}
+			arr[i], arr[j] = arr[j], arr[i]
+			i++
+			j--
+		}
+
+		if j+1 < len(arr)-j-1 {
+			quickSort(arr[:j+1])
+			arr = arr[j+1:]
+		} else {
+			quickSort(arr[j+1:])
+			arr = arr[:j+1]
+		}
+	}
+	insertionSort(arr)
+}
+
+// Mergesort: top-down, ping-ponging between arr and a scratch buffer
+// so each level does a single copy.
+func mergeSort(arr []int64) {
+	scratch := make([]int64, len(arr))
+	copy(scratch, arr)
+	mergeSortInto(scratch, arr)
+}
+
+// mergeSortInto sorts src into dst. Both hold the same elements on entry.
+func mergeSortInto(src, dst []int64) {
+	if len(dst) <= insertionCutoff {
+		insertionSort(dst)
+		return
+	}
+	mid := len(dst) / 2
+	mergeSortInto(dst[:mid], src[:mid])
+	mergeSortInto(dst[mid:], src[mid:])
+
+	left, right := src[:mid], src[mid:]
+	i, j := 0, 0
+	for k := range dst {
+		if j >= len(right) || (i < len(left) && left[i] <= right[j]) {
+			dst[k] = left[i]
+			i++
+		} else {
+			dst[k] = right[j]
+			j++
+		}
+	}
+}
+
+// Heapsort: in-place max-heap, sift-down on every extraction.
+func heapSort(arr []int64) {
+	n := len(arr)
+	for i := n/2 - 1; i >= 0; i-- {
+		siftDown(arr, i, n)
+	}
+	for end := n - 1; end > 0; end-- {
+		arr[0], arr[end] = arr[end], arr[0]
+		siftDown(arr, 0, end)
+	}
+}
+
+func siftDown(arr []int64, root, end int) {
+	for {
+		child := 2*root + 1
+		if child >= end {
+			return
+		}
+		if child+1 < end && arr[child] < arr[child+1] {
+			child++
+		}
+		if arr[root] >= arr[child] {
+			return
+		}
+		arr[root], arr[child] = arr[child], arr[root]
+		root = child
+	}
+}
+
+func sum(arr []int64) int64 {
+	var acc int64 = 0
+	for _, v := range arr {
+		acc += v
+	}
+	return acc
+}
+
+// Position-weighted sum: only matches across variants if the
+// order is identical, not just the elements.
+func checksum(arr []int64) int64 {
+	var acc int64 = 0
+	for i, v := range arr {
+		acc += v * int64(i+1)
+	}
+	return acc
+}
+
+// quicksort.mml's input: raw LCG output reduced mod 100000, so values
+// repeat and can be negative.
+func fillParity(arr []int64, seed int64) {
+	r := newLCG(seed)
+	for i := range arr {
+		arr[i] = r.next() % 100000
+	}
+}
+
+var presets = sizePresets{
+	"small":  {"n": "100000"},
+	"medium": {"n": "1000000"},
+	"large":  {"n": "10000000"},
+	"huge":   {"n": "50000000"},
+}
+
+func main() {
+	n := flag.Int("n", 1_000_000, "number of elements to sort")
+	seed := flag.Int64("seed", 42, "LCG seed for the input")
+	variant := flag.String("variant", "quick", "quick, merge, heap, slices or sortslice")
+	parity := flag.Bool("parity", false, "sort quicksort.mml's input and print exactly what it prints")
+	verify := flag.Bool("verify", false, "with -parity, check the median against expected.go")
+	parseWithSize(presets)
+	defer startProfiling()()
+
+	if *verify && !*parity {
+		fmt.Fprintln(os.Stderr, "-verify needs -parity; the default run checks itself")
+		exit(2)
+	}
+	if *parity && *n < 1 {
+		fmt.Fprintln(os.Stderr, "-parity needs at least one element for the median")
+		exit(2)
+	}
+
+	arr := make([]int64, *n)
+	if *parity {
+		fillParity(arr, *seed)
+	} else {
+		fillRandom(arr, *seed)
+	}
+	before := sum(arr)
+
+	start := startKernel()
+	switch *variant {
+	case "quick":
+		quickSort(arr)
+	case "merge":
+		mergeSort(arr)
+	case "heap":
+		heapSort(arr)
+	case "slices":
+		slices.Sort(arr)
+	case "sortslice":
+		sort.Slice(arr, func(i, j int) bool { return arr[i] < arr[j] })
+	default:
+		fmt.Fprintf(os.Stderr, "unknown variant %q\n", *variant)
+		exit(2)
+	}
+	elapsed := time.Since(start)
+
+	if !slices.IsSorted(arr) {
+		fmt.Fprintf(os.Stderr, "%s: output is not sorted\n", *variant)
+		exit(1)
+	}
+	if sum(arr) != before {
+		fmt.Fprintf(os.Stderr, "%s: elements changed while sorting\n", *variant)
+		exit(1)
+	}
+
+	if *parity {
+		median := arr[len(arr)/2]
+		fmt.Printf("Median checksum: %d\n", median)
+		reportKernel("sort", *variant, elapsed, median)
+		if *verify {
+			verifyResult("quicksort", fmt.Sprintf("n=%d seed=%d", *n, *seed), median)
+		}
+		return
+	}
+	sorted := checksum(arr)
+	fmt.Printf("Sort checksum (%s): %d\n", *variant, sorted)
+	reportKernel("sort", *variant, elapsed, sorted)
+}
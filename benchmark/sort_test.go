@@ -0,0 +1,19 @@
+package main
+
+import "testing"
+
+// The quicksort entry is the median of -parity's input, which every sort
+// variant has to agree on.
+func TestSortExpected(t *testing.T) {
+	sorts := map[string]func([]int64){"quick": quickSort, "merge": mergeSort, "heap": heapSort}
+	for name, sorter := range sorts {
+		t.Run(name, func(t *testing.T) {
+			testExpected(t, "quicksort", "n", 1_000_000, func(t *testing.T, c expectedCase) int64 {
+				arr := make([]int64, c.int(t, "n"))
+				fillParity(arr, c.int(t, "seed"))
+				sorter(arr)
+				return arr[len(arr)/2]
+			})
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+)
+
+// STREAM's four kernels (McCalpin), over slices far larger than cache:
+//
+//	copy:  c = a
+//	scale: b = s*c
+//	add:   c = a + b
+//	triad: a = b + s*c
+//
+// Bandwidth counts the bytes each kernel names (2 or 3 arrays per
+// element), not write-allocate traffic, same as the original.
+
+type number interface {
+	~int64 | ~float64
+}
+
+func copyKernel[T number](c, a []T) {
+	for i := range c {
+		c[i] = a[i]
+	}
+}
+
+func scaleKernel[T number](b, c []T, s T) {
+	for i := range b {
+		b[i] = s * c[i]
+	}
+}
+
+func addKernel[T number](c, a, b []T) {
+	for i := range c {
+		c[i] = a[i] + b[i]
+	}
+}
+
+func triadKernel[T number](a, b, c []T, s T) {
+	for i := range a {
+		a[i] = b[i] + s*c[i]
+	}
+}
+
+var kernelNames = []string{"copy", "scale", "add", "triad"}
+
+// Arrays per element each kernel moves
+var kernelArrays = []int{2, 2, 3, 3}
+
+// Runs every kernel reps times and returns the best time per kernel,
+// as STREAM reports it, plus the final arrays for validation.
+func stream[T number](n, reps int) ([4]time.Duration, []T, []T, []T) {
+	a := make([]T, n)
+	b := make([]T, n)
+	c := make([]T, n)
+	for i := range a {
+		a[i], b[i], c[i] = 1, 2, 0
+	}
+	var s T = 3
+
+	best := [4]time.Duration{}
+	for rep := 0; rep < reps; rep++ {
+		for k := range kernelNames {
+			start := startKernel()
+			switch k {
+			case 0:
+				copyKernel(c, a)
+			case 1:
+				scaleKernel(b, c, s)
+			case 2:
+				addKernel(c, a, b)
+			case 3:
+				triadKernel(a, b, c, s)
+			}
+			if t := time.Since(start); rep == 0 || t < best[k] {
+				best[k] = t
+			}
+		}
+	}
+	return best, a, b, c
+}
+
+// Replays the kernels on scalars; every element must end up with these values.
+func expected[T number](reps int) (T, T, T) {
+	var a, b, c, s T = 1, 2, 0, 3
+	for rep := 0; rep < reps; rep++ {
+		c = a
+		b = s * c
+		c = a + b
+		a = b + s*c
+	}
+	return a, b, c
+}
+
+func validate[T number](reps int, a, b, c []T) bool {
+	wa, wb, wc := expected[T](reps)
+	for i := range a {
+		if a[i] != wa || b[i] != wb || c[i] != wc {
+			return false
+		}
+	}
+	return true
+}
+
+var presets = sizePresets{
+	"small":  {"n": "4194304"},
+	"medium": {"n": "16777216"},
+	"large":  {"n": "67108864"},
+	"huge":   {"n": "268435456"},
+}
+
+func main() {
+	n := flag.Int("n", 1<<24, "elements per array")
+	reps := flag.Int("reps", 10, "runs of each kernel; the best one is reported")
+	variant := flag.String("variant", "float64", "float64 or int64")
+	parseWithSize(presets)
+	defer startProfiling()()
+
+	if *n < 1 || *reps < 1 {
+		fmt.Fprintln(os.Stderr, "-n and -reps must be at least 1")
+		exit(2)
+	}
+
+	var best [4]time.Duration
+	var ok bool
+	var sum float64
+	switch *variant {
+	case "float64":
+		var a, b, c []float64
+		best, a, b, c = stream[float64](*n, *reps)
+		ok = validate(*reps, a, b, c)
+		sum = a[0] + b[0] + c[0]
+	case "int64":
+		var a, b, c []int64
+		best, a, b, c = stream[int64](*n, *reps)
+		ok = validate(*reps, a, b, c)
+		sum = float64(a[0] + b[0] + c[0])
+	default:
+		fmt.Fprintf(os.Stderr, "unknown variant %q\n", *variant)
+		exit(2)
+	}
+	if !ok {
+		fmt.Fprintf(os.Stderr, "%s: arrays do not match the expected values\n", *variant)
+		exit(1)
+	}
+
+	fmt.Printf("STREAM (%s, %d elements): validated, a+b+c = %g\n", *variant, *n, sum)
+
+	// Bandwidth depends on the machine, so it stays off stdout
+	for k, name := range kernelNames {
+		bytes := float64(kernelArrays[k] * 8 * *n)
+		fmt.Fprintf(os.Stderr, "%-6s %8.2f GB/s (best %v)\n", name+":", bytes/best[k].Seconds()/1e9, best[k])
+		reportKernel("stream", *variant+"-"+name, best[k], sum)
+	}
+}
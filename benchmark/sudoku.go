@@ -0,0 +1,208 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math/bits"
+	"os"
+	"time"
+)
+
+// Hard puzzles from well-known collections, row-major, '.' for blanks.
+var puzzles = []string{
+	"8..........36......7..9.2...5...7.......457.....1...3...1....68..85...1..9....4..",
+	"4.....8.5.3..........7......2.....6.....8.4......1.......6.3.7.5..2.....1.4......",
+	"52...6.........7.13...........4..8..6......5...........418.........3..2...87.....",
+	"6.....8.3.4.7.................5.4.7.3..2.....1.6.......2.....5.....8.6......1....",
+	"48.3............71.2.......7.5....6....2..8.............1.76...3.....4......5....",
+	"85...24..72......9..4.........1.7..23.5...9...4...........8..7..17..........36.4.",
+	"..53.....8......2..7..1.5..4....53...1..7...6..32...8..6.5....9..4....3......97..",
+}
+
+func parse(p string) [81]int8 {
+	var grid [81]int8
+	for i := 0; i < 81; i++ {
+		if p[i] != '.' {
+			grid[i] = int8(p[i] - '0')
+		}
+	}
+	return grid
+}
+
+// Plain backtracking: first empty cell in row-major order,
+// digits tried 1..9, conflicts found by scanning row, column and box.
+func canPlace(grid *[81]int8, cell int, d int8) bool {
+	row, col := cell/9, cell%9
+	for i := 0; i < 9; i++ {
+		if grid[row*9+i] == d || grid[i*9+col] == d {
+			return false
+		}
+	}
+	br, bc := row/3*3, col/3*3
+	for r := br; r < br+3; r++ {
+		for c := bc; c < bc+3; c++ {
+			if grid[r*9+c] == d {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func solvePlain(grid *[81]int8, from int) bool {
+	cell := from
+	for cell < 81 && grid[cell] != 0 {
+		cell++
+	}
+	if cell == 81 {
+		return true
+	}
+	for d := int8(1); d <= 9; d++ {
+		if canPlace(grid, cell, d) {
+			grid[cell] = d
+			if solvePlain(grid, cell+1) {
+				return true
+			}
+		}
+	}
+	grid[cell] = 0
+	return false
+}
+
+// Bitmask variant: bit d of rows/cols/boxes marks digit d as used.
+// Each step branches on the empty cell with the fewest candidates,
+// which prunes far more than row-major order.
+type masks struct {
+	rows, cols, boxes [9]uint16
+}
+
+func box(cell int) int {
+	return cell/27*3 + cell%9/3
+}
+
+func (m *masks) set(cell int, d int8) {
+	bit := uint16(1) << d
+	m.rows[cell/9] |= bit
+	m.cols[cell%9] |= bit
+	m.boxes[box(cell)] |= bit
+}
+
+func (m *masks) clear(cell int, d int8) {
+	bit := ^(uint16(1) << d)
+	m.rows[cell/9] &= bit
+	m.cols[cell%9] &= bit
+	m.boxes[box(cell)] &= bit
+}
+
+func (m *masks) candidates(cell int) uint16 {
+	used := m.rows[cell/9] | m.cols[cell%9] | m.boxes[box(cell)]
+	return ^used & 0x3FE
+}
+
+func solveMasks(grid *[81]int8, m *masks) bool {
+	best, bestCount := -1, 10
+	var bestCands uint16
+	for cell := 0; cell < 81; cell++ {
+		if grid[cell] != 0 {
+			continue
+		}
+		c := m.candidates(cell)
+		n := bits.OnesCount16(c)
+		if n < bestCount {
+			best, bestCount, bestCands = cell, n, c
+			if n <= 1 {
+				break
+			}
+		}
+	}
+	if best < 0 {
+		return true
+	}
+	for c := bestCands; c != 0; c &= c - 1 {
+		d := int8(bits.TrailingZeros16(c))
+		grid[best] = d
+		m.set(best, d)
+		if solveMasks(grid, m) {
+			return true
+		}
+		m.clear(best, d)
+	}
+	grid[best] = 0
+	return false
+}
+
+// A solution is valid if it keeps the givens and every row,
+// column and box holds each digit exactly once.
+func valid(puzzle string, grid *[81]int8) bool {
+	for i := 0; i < 81; i++ {
+		if puzzle[i] != '.' && int8(puzzle[i]-'0') != grid[i] {
+			return false
+		}
+	}
+	for u := 0; u < 9; u++ {
+		var row, col, blk uint16
+		for i := 0; i < 9; i++ {
+			row |= 1 << grid[u*9+i]
+			col |= 1 << grid[i*9+u]
+			blk |= 1 << grid[(u/3*3+i/3)*9+u%3*3+i%3]
+		}
+		if row != 0x3FE || col != 0x3FE || blk != 0x3FE {
+			return false
+		}
+	}
+	return true
+}
+
+var presets = sizePresets{
+	"small":  {"reps": "1"},
+	"medium": {"reps": "5"},
+	"large":  {"reps": "20"},
+	"huge":   {"reps": "100"},
+}
+
+func main() {
+	reps := flag.Int("reps", 1, "times to solve the whole puzzle set")
+	variant := flag.String("variant", "bitmask", "plain or bitmask")
+	parseWithSize(presets)
+	defer startProfiling()()
+
+	var solve func(*[81]int8) bool
+	switch *variant {
+	case "plain":
+		solve = func(g *[81]int8) bool { return solvePlain(g, 0) }
+	case "bitmask":
+		solve = func(g *[81]int8) bool {
+			var m masks
+			for cell, d := range g {
+				if d != 0 {
+					m.set(cell, d)
+				}
+			}
+			return solveMasks(g, &m)
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "unknown variant %q\n", *variant)
+		exit(2)
+	}
+
+	var solved, checksum int64 = 0, 0
+	var elapsed time.Duration
+	for rep := 0; rep < *reps; rep++ {
+		for i, p := range puzzles {
+			grid := parse(p)
+			start := startKernel()
+			ok := solve(&grid)
+			elapsed += time.Since(start)
+			if !ok || !valid(p, &grid) {
+				fmt.Fprintf(os.Stderr, "%s: puzzle %d not solved correctly\n", *variant, i)
+				exit(1)
+			}
+			solved++
+			// Project Euler 96 style: the 3-digit number in the top-left corner
+			checksum += int64(grid[0])*100 + int64(grid[1])*10 + int64(grid[2])
+		}
+	}
+
+	fmt.Printf("Sudoku solved (%s): %d, checksum: %d\n", *variant, solved, checksum)
+	reportKernel("sudoku", *variant, elapsed, checksum)
+}